@@ -4,9 +4,14 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math/rand"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cocoonmail/cocoonmail-go/helpers/mail"
@@ -18,6 +23,12 @@ const (
 	Version        = "3.16.1"
 	rateLimitRetry = 5
 	rateLimitSleep = 1100
+
+	// DefaultMaxRetryAfter caps the Retry-After wait unless WithMaxRetryAfter is used
+	DefaultMaxRetryAfter = time.Minute
+
+	// maxBackoff caps the exponential backoff delay between retries
+	maxBackoff = time.Minute
 )
 
 type options struct {
@@ -30,6 +41,15 @@ type options struct {
 // Client is the Cocoonmail Go client
 type Client struct {
 	rest.Request
+
+	maxAttempts   int           // set by WithRetry
+	baseDelay     time.Duration // set by WithRetry
+	maxRetryAfter time.Duration // set by WithMaxRetryAfter
+	httpClient    *rest.Client  // set by WithHTTPClient; nil means DefaultClient
+	timeout       time.Duration // set by WithTimeout; zero means none
+	dryRun        bool          // set by SetDryRun
+	allowedTypes  []string      // set by WithAttachmentMIMEPolicy
+	deniedTypes   []string      // set by WithAttachmentMIMEPolicy
 }
 
 func (o *options) baseURL() string {
@@ -45,9 +65,9 @@ func requestNew(options options) rest.Request {
 		"Accept":        "application/json",
 	}
 
-	// if len(options.Subuser) != 0 {
-	// 	requestHeaders["On-Behalf-Of"] = options.Subuser
-	// }
+	if subuser := strings.TrimSpace(options.Subuser); subuser != "" {
+		requestHeaders["On-Behalf-Of"] = subuser
+	}
 
 	return rest.Request{
 		BaseURL: options.baseURL(),
@@ -55,35 +75,345 @@ func requestNew(options options) rest.Request {
 	}
 }
 
-// Send sends an email through Cocoonmail
-func (cl *Client) Send(email *mail.MailSendRequest) (*rest.Response, error) {
+// SetSubuser sends the client's requests on behalf of the named subuser,
+// through the On-Behalf-Of header. An empty name removes the header.
+func (cl *Client) SetSubuser(name string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		delete(cl.Headers, "On-Behalf-Of")
+		return
+	}
+	if cl.Headers == nil {
+		cl.Headers = map[string]string{}
+	}
+	cl.Headers["On-Behalf-Of"] = name
+}
+
+// reservedHeaders are set by the client and cannot be changed with SetHeader or AddHeaders
+var reservedHeaders = map[string]bool{
+	"Authorization": true,
+	"Content-Type":  true,
+}
+
+// SetHeader sets a header sent with every request of the client, e.g. for
+// tracing or tenant IDs. The reserved Authorization and Content-Type
+// headers cannot be overridden and are rejected with an error.
+func (cl *Client) SetHeader(key, value string) error {
+	return cl.AddHeaders(map[string]string{key: value})
+}
+
+// AddHeaders sets several headers as SetHeader does. If any of them is
+// reserved, none is set.
+func (cl *Client) AddHeaders(headers map[string]string) error {
+	for key := range headers {
+		if reservedHeaders[http.CanonicalHeaderKey(key)] {
+			return fmt.Errorf("header %q is reserved and cannot be overridden", key)
+		}
+	}
+	if cl.Headers == nil {
+		cl.Headers = map[string]string{}
+	}
+	for key, value := range headers {
+		cl.Headers[http.CanonicalHeaderKey(key)] = value
+	}
+	return nil
+}
+
+// SetDryRun turns dry-run mode on or off. In dry-run mode nothing is sent:
+// Send and the other send methods build the request as usual and return a
+// synthetic 200 response whose Body is the request body as it would have
+// been sent, so tests can check payloads without touching the network.
+// Warm does nothing in dry-run mode.
+func (cl *Client) SetDryRun(enabled bool) {
+	cl.dryRun = enabled
+}
+
+// Send sends an email through Cocoonmail.
+// Non-2xx responses are returned as a *rest.RestError holding the response,
+// and a 2xx response whose body is not valid JSON with an error wrapping
+// ErrInvalidResponse.
+func (cl *Client) Send(email *mail.MailSendRequest) (*MailSendResponse, error) {
 	return cl.SendWithContext(context.Background(), email)
 }
 
 // SendWithContext sends an email through Cocoonmail with context.Context.
-func (cl *Client) SendWithContext(ctx context.Context, email *mail.MailSendRequest) (*rest.Response, error) {
-	cl.Body = mail.GetRequestBody(email)
+// If ctx is done before the send completes, the send is aborted and ctx.Err() is returned.
+func (cl *Client) SendWithContext(ctx context.Context, email *mail.MailSendRequest) (*MailSendResponse, error) {
+	request, err := cl.buildRequest(email)
+	if err != nil {
+		return nil, err
+	}
+	response, err := cl.sendRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return parseSendResponse(response)
+}
+
+// SendInRegion sends an email through the host of the given data residency
+// region, without changing the region the client sends to by default.
+func (cl *Client) SendInRegion(ctx context.Context, email *mail.MailSendRequest, region string) (*MailSendResponse, error) {
+	request, err := cl.buildRequest(email)
+	if err != nil {
+		return nil, err
+	}
+	request, err = SetDataResidency(request, region)
+	if err != nil {
+		return nil, err
+	}
+	response, err := cl.sendRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return parseSendResponse(response)
+}
+
+// NewFromTemplate creates a request for the given transactional template and
+// applies overrides to it, if not nil. It only builds the request; nothing
+// is sent and the result still needs validating before Send.
+func (cl *Client) NewFromTemplate(transactionalID string, overrides func(*mail.MailSendRequest)) *mail.MailSendRequest {
+	email := mail.NewMailSendRequest()
+	email.TransactionalID = transactionalID
+	if overrides != nil {
+		overrides(email)
+	}
+	return email
+}
+
+// Warm opens a connection to the API host with a HEAD request, so the first
+// sends of a burst don't pay for DNS and the TLS handshake. It relies on the
+// HTTP client keeping connections alive; any HTTP status counts as warmed,
+// and a failure only means the next send opens its own connection. In
+// dry-run mode it returns nil without connecting.
+func (cl *Client) Warm(ctx context.Context) error {
+	if cl.dryRun {
+		return nil
+	}
+	u, err := url.Parse(cl.BaseURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return errors.New("cannot warm a client without an absolute base URL")
+	}
+	_, err = cl.restClient().SendWithContext(ctx, rest.Request{
+		Method:  rest.Method(http.MethodHead),
+		BaseURL: u.Scheme + "://" + u.Host + "/",
+		Headers: map[string]string{"User-Agent": cl.Headers["User-Agent"]},
+	})
+	return err
+}
+
+// SendBody sends an already marshaled email body, such as the one of a mail.FrozenRequest.
+// A non-empty idempotencyKey is sent as the Idempotency-Key header.
+func (cl *Client) SendBody(ctx context.Context, body []byte, idempotencyKey string) (*rest.Response, error) {
+	request, err := cl.requestWithBody(body)
+	if err != nil {
+		return nil, err
+	}
+	return cl.sendRequest(ctx, withIdempotencyKey(request, idempotencyKey))
+}
+
+// sendRequest makes the request, retrying transient failures as set by WithRetry.
+// If ctx is done before the request completes, ctx.Err() is returned.
+// In dry-run mode it returns a 200 response echoing the request body, which
+// requestWithBody leaves uncompressed.
+func (cl *Client) sendRequest(ctx context.Context, request rest.Request) (*rest.Response, error) {
+	if cl.dryRun {
+		return &rest.Response{
+			StatusCode: http.StatusOK,
+			Body:       string(request.Body),
+			Headers:    map[string][]string{},
+		}, nil
+	}
+	ctx, cancel := cl.withTimeout(ctx)
+	defer cancel()
+	for attempt := 1; ; attempt++ {
+		response, err := cl.restClient().SendWithContext(ctx, request)
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err == nil && !retryableStatus(response.StatusCode) {
+			return response, nil
+		}
+		if attempt >= cl.maxAttempts {
+			return response, err
+		}
+		if err := sleepWithContext(ctx, cl.retryDelay(response, attempt)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// withTimeout bounds ctx by the timeout set by WithTimeout, if any.
+// The earlier of the two deadlines applies.
+func (cl *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if cl.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, cl.timeout)
+}
+
+// restClient returns the HTTP client sends go through: the one set by
+// WithHTTPClient, or else DefaultClient with DefaultTimeout applied if it
+// has no timeout, keeping its transport and other settings
+func (cl *Client) restClient() *rest.Client {
+	if cl.httpClient != nil {
+		return cl.httpClient
+	}
+	if DefaultClient.HTTPClient == nil || DefaultClient.HTTPClient.Timeout != 0 {
+		return DefaultClient
+	}
+	httpClient := *DefaultClient.HTTPClient
+	httpClient.Timeout = DefaultTimeout
+	return &rest.Client{HTTPClient: &httpClient}
+}
+
+// doJSON makes a request to the client's base URL followed by path, with in
+// marshaled as the JSON body if not nil, and decodes a JSON response into
+// out if not nil. Non-2xx responses are returned as a *rest.RestError.
+func (cl *Client) doJSON(ctx context.Context, method, path string, in, out interface{}) error {
+	request := cl.Request
+	request.Method = rest.Method(method)
+	request.BaseURL = cl.BaseURL + path
+	request.Body = nil
+	if in != nil {
+		body, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		request.Body = body
+	}
+	response, err := cl.sendRequest(ctx, request)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return &rest.RestError{Response: response}
+	}
+	if out == nil || response.Body == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(response.Body), out)
+}
+
+// retryDelay returns how long to wait before retrying after the given
+// attempt: the Retry-After of a 429, capped, or the exponential backoff.
+func (cl *Client) retryDelay(response *rest.Response, attempt int) time.Duration {
+	if response != nil && response.StatusCode == http.StatusTooManyRequests {
+		if d, ok := parseRetryAfter(response.Headers, time.Now()); ok {
+			limit := cl.maxRetryAfter
+			if limit <= 0 {
+				limit = DefaultMaxRetryAfter
+			}
+			if d > limit {
+				d = limit
+			}
+			return d
+		}
+	}
+	return backoff(cl.baseDelay, attempt)
+}
+
+// retryableStatus reports whether a response status is worth retrying
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the delay before the given retry: base * 2^(retry-1),
+// capped at maxBackoff, with jitter picking a value in its upper half.
+func backoff(base time.Duration, retry int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	d := base
+	for i := 1; i < retry && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// sleepWithContext waits for d, or returns ctx.Err() if ctx is done first
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// buildRequest copies the client request and sets the email as its body,
+// after checking its attachments against the WithAttachmentMIMEPolicy policy.
+func (cl *Client) buildRequest(email *mail.MailSendRequest) (rest.Request, error) {
+	if err := email.CheckAttachmentPolicy(cl.allowedTypes, cl.deniedTypes); err != nil {
+		return cl.Request, err
+	}
+	body, err := mail.MarshalRequest(email)
+	if err != nil {
+		return cl.Request, err
+	}
+	request, err := cl.requestWithBody(body)
+	if err != nil {
+		return request, err
+	}
+	return withIdempotencyKey(request, email.IdempotencyKey()), nil
+}
+
+// withIdempotencyKey sets the Idempotency-Key header on a copy of the
+// request headers, so the client headers are never mutated. An empty key
+// leaves the request unchanged.
+func withIdempotencyKey(request rest.Request, key string) rest.Request {
+	if key == "" {
+		return request
+	}
+	headers := make(map[string]string, len(request.Headers)+1)
+	for k, v := range request.Headers {
+		headers[k] = v
+	}
+	headers["Idempotency-Key"] = key
+	request.Headers = headers
+	return request
+}
+
+// requestWithBody copies the client request and sets its body.
+func (cl *Client) requestWithBody(body []byte) (rest.Request, error) {
+	request := cl.Request
+	request.Body = body
 	// when Content-Encoding header is set to "gzip"
 	// mail body is compressed using gzip according to
-
-	if cl.Headers["Content-Encoding"] == "gzip" {
+	// except in dry-run mode, which echoes the body as marshaled
+	if request.Headers["Content-Encoding"] == "gzip" && !cl.dryRun {
 		var gzipped bytes.Buffer
 		gz := gzip.NewWriter(&gzipped)
-		if _, err := gz.Write(cl.Body); err != nil {
-			return nil, err
+		if _, err := gz.Write(request.Body); err != nil {
+			return request, err
 		}
 		if err := gz.Flush(); err != nil {
-			return nil, err
+			return request, err
 		}
 		if err := gz.Close(); err != nil {
-			return nil, err
+			return request, err
 		}
 
-		cl.Body = gzipped.Bytes()
+		request.Body = gzipped.Bytes()
 	}
-	return MakeRequestWithContext(ctx, cl.Request)
+	return request, nil
 }
 
+// DefaultTimeout bounds each send of a Client built without WithHTTPClient
+// when DefaultClient sets no timeout of its own
+const DefaultTimeout = 30 * time.Second
+
 // DefaultClient is used if no custom HTTP client is defined
 var DefaultClient = rest.DefaultClient
 
@@ -0,0 +1,48 @@
+package cocoonmail
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cocoonmail/cocoonmail-go/helpers/mail"
+)
+
+// BatchResult is the outcome of one request of a SendBatch
+type BatchResult struct {
+	Index    int // position of the request in the batch
+	Response *MailSendResponse
+	Err      error
+}
+
+// SendBatch sends independent requests with at most concurrency of them in
+// flight (at least one). Results are returned in the order of reqs. Once ctx
+// is done, requests not yet started are not sent and report ctx.Err().
+func (cl *Client) SendBatch(ctx context.Context, reqs []*mail.MailSendRequest, concurrency int) []BatchResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]BatchResult, len(reqs))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, email := range reqs {
+		results[i].Index = i
+		if err := ctx.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		case slots <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(result *BatchResult, email *mail.MailSendRequest) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			result.Response, result.Err = cl.SendWithContext(ctx, email)
+		}(&results[i], email)
+	}
+	wg.Wait()
+	return results
+}
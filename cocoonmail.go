@@ -2,7 +2,10 @@ package cocoonmail
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
+	"sort"
+	"strings"
 
 	"github.com/cocoonmail/cocoonmail-go/rest"
 )
@@ -17,8 +20,25 @@ type CocoonmailOptions struct {
 
 // cocoonmail host map for different regions
 var allowedRegionsHostMap = map[string]string{
+	"ap":     "https://api.ap.cocoonmail.com",
 	"eu":     "https://api.eu.cocoonmail.com",
 	"global": "https://webhook.cocoonmail.com",
+	"us":     "https://api.us.cocoonmail.com",
+}
+
+// allowedRegionsList lists the keys of allowedRegionsHostMap in sorted
+// order, e.g. `"ap", "eu", "global" or "us"`
+func allowedRegionsList() string {
+	regions := make([]string, 0, len(allowedRegionsHostMap))
+	for region := range allowedRegionsHostMap {
+		regions = append(regions, fmt.Sprintf("%q", region))
+	}
+	sort.Strings(regions)
+	last := len(regions) - 1
+	if last <= 0 {
+		return strings.Join(regions, "")
+	}
+	return strings.Join(regions[:last], ", ") + " or " + regions[last]
 }
 
 // GetRequest
@@ -50,11 +70,30 @@ func createCocoonmailRequest(sgOptions CocoonmailOptions) rest.Request {
 	return requestNew(options)
 }
 
-// NewSendClient constructs a new Cocoonmail client given an API key
-func NewSendClient(key string) *Client {
-	request := GetRequest(key, "/webhook/mail/send", "")
-	request.Method = "POST"
-	return &Client{request}
+// NewSendClient constructs a new Cocoonmail client given an API key and options
+func NewSendClient(key string, opts ...ClientOption) *Client {
+	client := newClient(key, "/webhook/mail/send", opts)
+	client.Method = "POST"
+	return client
+}
+
+// newClient constructs a Client for endpoint and applies opts to it
+func newClient(key, endpoint string, opts []ClientOption) *Client {
+	client := &Client{Request: GetRequest(key, endpoint, "")}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return client
+}
+
+// setDataResidency sends the requests of cl to the given region
+func (cl *Client) setDataResidency(region string) error {
+	request, err := SetDataResidency(cl.Request, region)
+	if err != nil {
+		return err
+	}
+	cl.Request = request
+	return nil
 }
 
 // extractEndpoint extracts the endpoint from a baseURL
@@ -69,18 +108,20 @@ func extractEndpoint(link string) (string, error) {
 
 // SetDataResidency modifies the host as per the region
 /*
- * This allows support for global and eu regions only. This set will likely expand in the future.
+ * This allows support for the global, eu, us and ap regions.
  * Global should be the default
  * Global region means the message should be sent through:
- * HTTP: api.cocoonmail.com
- * EU region means the message should be sent through:
- * HTTP: api.eu.cocoonmail.com
+ * HTTP: webhook.cocoonmail.com
+ * EU, US and AP regions mean the message should be sent through:
+ * HTTP: api.eu.cocoonmail.com, api.us.cocoonmail.com, api.ap.cocoonmail.com
+ * Regions are matched case-insensitively, ignoring surrounding spaces.
+ * Use SetDataResidencyHost for any other host.
  */
 // @return [Request] the modified request object
 func SetDataResidency(request rest.Request, region string) (rest.Request, error) {
-	regionalHost, present := allowedRegionsHostMap[region]
+	regionalHost, present := allowedRegionsHostMap[strings.ToLower(strings.TrimSpace(region))]
 	if !present {
-		return request, errors.New("error: region can only be \"eu\" or \"global\"")
+		return request, errors.New("error: region can only be " + allowedRegionsList())
 	}
 	endpoint, err := extractEndpoint(request.BaseURL)
 	if err != nil {
@@ -89,3 +130,23 @@ func SetDataResidency(request rest.Request, region string) (rest.Request, error)
 	request.BaseURL = regionalHost + endpoint
 	return request, nil
 }
+
+// SetDataResidencyHost sends the request through host instead of a named
+// region, e.g. for a self-hosted or preview endpoint. host must be an
+// absolute https URL such as "https://api.preview.cocoonmail.com".
+// @return [Request] the modified request object
+func SetDataResidencyHost(request rest.Request, host string) (rest.Request, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return request, err
+	}
+	if u.Scheme != "https" || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return request, fmt.Errorf("error: host %q must be an absolute https URL", host)
+	}
+	endpoint, err := extractEndpoint(request.BaseURL)
+	if err != nil {
+		return request, err
+	}
+	request.BaseURL = strings.TrimRight(host, "/") + endpoint
+	return request, nil
+}
@@ -1,19 +1,22 @@
 package cocoonmail
 
 import (
-	// "context"
+	"context"
 	// "encoding/json"
+	"errors"
 	"fmt"
-	// "net/http"
-	// "net/http/httptest"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	// "strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
-	// "github.com/cocoonmail/cocoonmail-go/helpers/mail"
-	// "github.com/cocoonmail/cocoonmail-go/rest"
+	"github.com/cocoonmail/cocoonmail-go/helpers/mail"
+	"github.com/cocoonmail/cocoonmail-go/rest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -3947,3 +3950,854 @@ func TestLicenseYear(t *testing.T) {
 // 	}
 // 	assert.Equal(t, 200, response.StatusCode, "Wrong status code returned")
 // }
+
+// captureTransport records outgoing requests instead of sending them.
+type captureTransport struct {
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (c *captureTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.requests = append(c.requests, r)
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(r.Body)
+	}
+	c.bodies = append(c.bodies, body)
+	return &http.Response{
+		StatusCode: http.StatusAccepted,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"message":"queued"}`)),
+	}, nil
+}
+
+// useCaptureTransport routes DefaultClient through a captureTransport until the test ends.
+func useCaptureTransport(t *testing.T) *captureTransport {
+	capture := &captureTransport{}
+	previous := DefaultClient.HTTPClient
+	DefaultClient.HTTPClient = &http.Client{Transport: capture}
+	t.Cleanup(func() { DefaultClient.HTTPClient = previous })
+	return capture
+}
+
+func TestSendInRegion(t *testing.T) {
+	capture := useCaptureTransport(t)
+	client := NewSendClient("API_KEY")
+	email := mail.NewMailSendRequest().AddRecipient(mail.NewMailRecipient("Jane", "jane@example.com"))
+
+	response, err := client.SendInRegion(context.Background(), email, "eu")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusAccepted, response.StatusCode)
+	assert.Equal(t, "https://api.eu.cocoonmail.com/webhook/mail/send", capture.requests[0].URL.String())
+	assert.Equal(t, "https://webhook.cocoonmail.com/webhook/mail/send", client.BaseURL, "client default region should not change")
+	assert.Nil(t, client.Body, "client request body should not be mutated")
+
+	_, err = client.SendInRegion(context.Background(), email, "mars")
+	assert.NotNil(t, err, "unknown regions should be rejected")
+	assert.Len(t, capture.requests, 1, "no request should be sent for an unknown region")
+}
+
+func TestSendFrozenRequest(t *testing.T) {
+	capture := useCaptureTransport(t)
+	client := NewSendClient("API_KEY")
+	email := mail.NewMailSendRequest().AddRecipient(mail.NewMailRecipient("Jane", "jane@example.com")).
+		SetIdempotencyKey("order-42")
+	frozen, err := email.Freeze()
+	assert.Nil(t, err)
+	email.SetReplyTo("changed@example.com")
+
+	response, err := frozen.Send(context.Background(), client)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusAccepted, response.StatusCode)
+	assert.Equal(t, frozen.Bytes(), capture.bodies[0])
+	assert.Equal(t, "Bearer API_KEY", capture.requests[0].Header.Get("Authorization"))
+	assert.Equal(t, "order-42", capture.requests[0].Header.Get("Idempotency-Key"))
+}
+
+func TestWarm(t *testing.T) {
+	var method, path, auth string
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, auth = r.Method, r.URL.Path, r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer fakeServer.Close()
+
+	client := NewSendClient("API_KEY")
+	client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+	assert.Nil(t, client.Warm(context.Background()), "any HTTP status should count as warmed")
+	assert.Equal(t, http.MethodHead, method)
+	assert.Equal(t, "/", path)
+	assert.Empty(t, auth, "warming should not send credentials")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NotNil(t, client.Warm(ctx))
+
+	client.BaseURL = "/webhook/mail/send"
+	assert.NotNil(t, client.Warm(context.Background()))
+}
+
+func TestNewFromTemplate(t *testing.T) {
+	capture := useCaptureTransport(t)
+	client := NewSendClient("API_KEY")
+
+	email := client.NewFromTemplate("welcome-v2", func(m *mail.MailSendRequest) {
+		m.SetReplyTo("support@example.com").
+			AddAttachment(mail.NewMailAttachment("terms.pdf", "application/pdf", "ZGF0YQ=="))
+	})
+	assert.Equal(t, "welcome-v2", email.TransactionalID)
+	assert.Equal(t, "support@example.com", email.ReplyTo)
+	assert.Len(t, email.Attachments, 1)
+	assert.NotNil(t, email.To, "builder invariants should hold")
+	assert.Empty(t, capture.requests, "building should not touch the network")
+
+	email = client.NewFromTemplate("welcome-v2", nil)
+	assert.Equal(t, "welcome-v2", email.TransactionalID)
+}
+
+func TestSend(t *testing.T) {
+	var body []byte
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, `{"message_id":"msg-123"}`)
+	}))
+	defer fakeServer.Close()
+
+	client := NewSendClient("API_KEY")
+	client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+	email := mail.NewMailSendRequest().
+		AddRecipient(mail.NewMailRecipient("Jane", "jane@example.com")).
+		SetSubject("Hello")
+
+	response, err := client.Send(email)
+	assert.Nil(t, err)
+	assert.Equal(t, "msg-123", response.MessageID)
+	assert.Equal(t, http.StatusAccepted, response.StatusCode)
+	assert.Equal(t, mail.GetRequestBody(email), body)
+}
+
+func TestParseSendResponseInvalidBody(t *testing.T) {
+	response, err := parseSendResponse(&rest.Response{StatusCode: http.StatusAccepted, Body: "<html>" + strings.Repeat("x", 100)})
+	if assert.NotNil(t, err) {
+		assert.True(t, errors.Is(err, ErrInvalidResponse))
+		assert.Contains(t, err.Error(), "status 202")
+		assert.Contains(t, err.Error(), `"<html>xxx`)
+		assert.NotContains(t, err.Error(), strings.Repeat("x", 100), "only a snippet of the body should be quoted")
+	}
+	if assert.NotNil(t, response, "the accepted response should still be returned") {
+		assert.Equal(t, http.StatusAccepted, response.StatusCode)
+		assert.Empty(t, response.MessageID)
+	}
+
+	for _, body := range []string{"", " \n"} {
+		_, err := parseSendResponse(&rest.Response{StatusCode: http.StatusAccepted, Body: body})
+		assert.Nil(t, err, "an empty body %q is not an invalid response", body)
+	}
+}
+
+func TestSendTruncatedResponse(t *testing.T) {
+	for _, body := range []string{`{"message_id":"msg-`, `{"message_id":`, `queued`} {
+		fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprint(w, body)
+		}))
+
+		client := NewSendClient("API_KEY")
+		client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+		response, err := client.Send(mail.NewMailSendRequest())
+		fakeServer.Close()
+
+		if assert.True(t, errors.Is(err, ErrInvalidResponse), "body %q should be an invalid response", body) {
+			assert.Contains(t, err.Error(), "status 202")
+			assert.Contains(t, err.Error(), fmt.Sprintf("%q", body))
+		}
+		var restErr *rest.RestError
+		assert.False(t, errors.As(err, &restErr), "a body we cannot parse is not an API error")
+		if assert.NotNil(t, response) {
+			assert.Equal(t, http.StatusAccepted, response.StatusCode)
+		}
+	}
+}
+
+func TestSendErrorResponse(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"invalid recipient"}`)
+	}))
+	defer fakeServer.Close()
+
+	client := NewSendClient("API_KEY")
+	client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+
+	response, err := client.Send(mail.NewMailSendRequest())
+	assert.Nil(t, response)
+	var restErr *rest.RestError
+	if assert.True(t, errors.As(err, &restErr), "non-2xx responses should be a *rest.RestError") {
+		assert.Equal(t, http.StatusBadRequest, restErr.Response.StatusCode)
+		assert.Equal(t, `{"error":"invalid recipient"}`, restErr.Response.Body)
+	}
+}
+
+func TestSendWithContextCancel(t *testing.T) {
+	started := make(chan struct{})
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body) // nolint
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer fakeServer.Close()
+
+	client := NewSendClient("API_KEY")
+	client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	response, err := client.SendWithContext(ctx, mail.NewMailSendRequest())
+	assert.Nil(t, response)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestSendMarshalError(t *testing.T) {
+	capture := useCaptureTransport(t)
+	client := NewSendClient("API_KEY")
+	email := mail.NewMailSendRequest().SetCustomParameter("callback", make(chan int))
+
+	response, err := client.Send(email)
+	assert.Nil(t, response)
+	assert.NotNil(t, err, "marshaling errors should be returned")
+	assert.Empty(t, capture.requests, "nothing should be sent when marshaling fails")
+}
+
+func TestSendWithRetry(t *testing.T) {
+	attempts := 0
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, `{"message_id":"msg-123"}`)
+	}))
+	defer fakeServer.Close()
+
+	client := NewSendClient("API_KEY", WithRetry(3, time.Millisecond))
+	client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+
+	response, err := client.Send(mail.NewMailSendRequest())
+	assert.Nil(t, err)
+	assert.Equal(t, "msg-123", response.MessageID)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestSendWithRetryStopsOnClientError(t *testing.T) {
+	attempts := 0
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer fakeServer.Close()
+
+	client := NewSendClient("API_KEY", WithRetry(3, time.Millisecond))
+	client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+
+	_, err := client.Send(mail.NewMailSendRequest())
+	var restErr *rest.RestError
+	assert.True(t, errors.As(err, &restErr))
+	assert.Equal(t, 1, attempts, "4xx responses should not be retried")
+}
+
+func TestSendWithRetryExhausted(t *testing.T) {
+	attempts := 0
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer fakeServer.Close()
+
+	client := NewSendClient("API_KEY", WithRetry(2, time.Millisecond))
+	client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+
+	_, err := client.Send(mail.NewMailSendRequest())
+	var restErr *rest.RestError
+	if assert.True(t, errors.As(err, &restErr)) {
+		assert.Equal(t, http.StatusBadGateway, restErr.Response.StatusCode)
+	}
+	assert.Equal(t, 2, attempts)
+}
+
+func TestSendWithRetryConnectionError(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	baseURL := fakeServer.URL + "/webhook/mail/send"
+	fakeServer.Close()
+
+	client := NewSendClient("API_KEY", WithRetry(3, time.Millisecond))
+	client.BaseURL = baseURL
+
+	_, err := client.Send(mail.NewMailSendRequest())
+	assert.NotNil(t, err)
+}
+
+func TestSendWithRetryRespectsContext(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer fakeServer.Close()
+
+	client := NewSendClient("API_KEY", WithRetry(10, time.Second))
+	client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.SendWithContext(ctx, mail.NewMailSendRequest())
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < time.Second, "the backoff should stop at the context deadline")
+}
+
+func TestBackoff(t *testing.T) {
+	for retry := 1; retry <= 4; retry++ {
+		full := 100 * time.Millisecond << (retry - 1)
+		d := backoff(100*time.Millisecond, retry)
+		assert.True(t, d >= full/2 && d <= full, "retry %d waited %v", retry, d)
+	}
+	assert.Equal(t, time.Duration(0), backoff(0, 1))
+	for _, retry := range []int{20, 64, 100, 1000} {
+		d := backoff(100*time.Millisecond, retry)
+		assert.True(t, d >= maxBackoff/2 && d <= maxBackoff, "retry %d waited %v, want it capped", retry, d)
+	}
+}
+
+func TestSendRetryAfter(t *testing.T) {
+	for _, retryAfter := range []string{"0", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)} {
+		attempts := 0
+		fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts == 1 {
+				w.Header().Set("Retry-After", retryAfter)
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		}))
+
+		client := NewSendClient("API_KEY", WithRetry(2, time.Hour))
+		client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+		start := time.Now()
+		_, err := client.Send(mail.NewMailSendRequest())
+		assert.Nil(t, err, "Retry-After %q", retryAfter)
+		assert.Equal(t, 2, attempts)
+		assert.True(t, time.Since(start) < time.Second, "Retry-After should replace the backoff delay")
+		fakeServer.Close()
+	}
+}
+
+func TestSendRetryAfterCapAndExhausted(t *testing.T) {
+	attempts := 0
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer fakeServer.Close()
+
+	client := NewSendClient("API_KEY", WithRetry(3, time.Millisecond), WithMaxRetryAfter(10*time.Millisecond))
+	client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+	start := time.Now()
+	_, err := client.Send(mail.NewMailSendRequest())
+	assert.True(t, time.Since(start) < time.Second, "the wait should be capped")
+	assert.Equal(t, 3, attempts)
+
+	var rateErr *RateLimitError
+	if assert.True(t, errors.As(err, &rateErr), "an exhausted 429 should be a *RateLimitError") {
+		assert.Equal(t, time.Hour, rateErr.RetryAfter)
+	}
+	var restErr *rest.RestError
+	assert.True(t, errors.As(err, &restErr), "a *RateLimitError should unwrap to a *rest.RestError")
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2030, 1, 2, 15, 0, 0, 0, time.UTC)
+	header := func(v string) map[string][]string { return map[string][]string{"Retry-After": {v}} }
+
+	d, ok := parseRetryAfter(header("120"), now)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, d)
+
+	d, ok = parseRetryAfter(header(now.Add(90*time.Second).Format(http.TimeFormat)), now)
+	assert.True(t, ok)
+	assert.Equal(t, 90*time.Second, d)
+
+	d, ok = parseRetryAfter(header(now.Add(-time.Hour).Format(http.TimeFormat)), now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), d)
+
+	for _, bad := range []string{"", "-1", "soon"} {
+		_, ok = parseRetryAfter(header(bad), now)
+		assert.False(t, ok, "Retry-After %q should be ignored", bad)
+	}
+	_, ok = parseRetryAfter(nil, now)
+	assert.False(t, ok)
+}
+
+func TestSendRateLimitHeaders(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("headers") == "1" {
+			w.Header().Set("X-RateLimit-Limit", "600")
+			w.Header().Set("X-RateLimit-Remaining", "599")
+			w.Header().Set("X-RateLimit-Reset", "1893456000")
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+
+	client := NewSendClient("API_KEY")
+	client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+	client.QueryParams = map[string]string{"headers": "1"}
+	response, err := client.Send(mail.NewMailSendRequest())
+	if assert.Nil(t, err) {
+		assert.Equal(t, 600, response.RateLimitLimit)
+		assert.Equal(t, 599, response.RateLimitRemaining)
+		assert.True(t, time.Unix(1893456000, 0).Equal(response.RateLimitReset))
+	}
+
+	client.QueryParams = nil
+	response, err = client.Send(mail.NewMailSendRequest())
+	if assert.Nil(t, err) {
+		assert.Zero(t, response.RateLimitLimit)
+		assert.Zero(t, response.RateLimitRemaining)
+		assert.True(t, response.RateLimitReset.IsZero())
+	}
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+
+	client := NewSendClient("API_KEY", WithHTTPClient(&http.Client{Timeout: 5 * time.Millisecond}))
+	client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+	_, err := client.Send(mail.NewMailSendRequest())
+	if assert.NotNil(t, err, "the custom client timeout should apply") {
+		assert.Contains(t, err.Error(), "Client.Timeout exceeded")
+	}
+
+	assert.Equal(t, DefaultTimeout, NewSendClient("API_KEY").restClient().HTTPClient.Timeout, "sends should have a default timeout")
+	assert.True(t, DefaultClient == rest.DefaultClient, "DefaultClient should still be rest.DefaultClient")
+	assert.Zero(t, DefaultClient.HTTPClient.Timeout, "DefaultClient should be left alone")
+
+	capture := useCaptureTransport(t)
+	client = NewSendClient("API_KEY", WithHTTPClient(nil))
+	_, err = client.Send(mail.NewMailSendRequest())
+	assert.Nil(t, err, "a nil client should fall back to DefaultClient")
+	assert.Len(t, capture.requests, 1)
+}
+
+func TestWithTimeout(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body) // nolint
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+
+	client := NewSendClient("API_KEY", WithTimeout(20*time.Millisecond))
+	client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+	start := time.Now()
+	_, err := client.Send(mail.NewMailSendRequest())
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestWithTimeoutDeadline(t *testing.T) {
+	client := NewSendClient("API_KEY", WithTimeout(time.Minute))
+	ctx, cancel := client.withTimeout(context.Background())
+	deadline, ok := ctx.Deadline()
+	cancel()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	// a caller deadline earlier than the timeout wins
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Second)
+	defer cancelParent()
+	want, _ := parent.Deadline()
+	ctx, cancel = client.withTimeout(parent)
+	deadline, _ = ctx.Deadline()
+	cancel()
+	assert.Equal(t, want, deadline)
+
+	// and a later one loses to the timeout
+	parent, cancelParent = context.WithTimeout(context.Background(), time.Hour)
+	defer cancelParent()
+	ctx, cancel = client.withTimeout(parent)
+	deadline, _ = ctx.Deadline()
+	cancel()
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	// no timeout leaves the context without a deadline
+	ctx, cancel = NewSendClient("API_KEY", WithTimeout(0)).withTimeout(context.Background())
+	_, ok = ctx.Deadline()
+	cancel()
+	assert.False(t, ok)
+}
+
+func TestSetDataResidencyRegions(t *testing.T) {
+	for region, host := range map[string]string{
+		"us": "https://api.us.cocoonmail.com",
+		"ap": "https://api.ap.cocoonmail.com",
+	} {
+		request, err := SetDataResidency(GetRequest("API_KEY", "/webhook/mail/send", ""), region)
+		assert.Nil(t, err, region)
+		assert.Equal(t, host+"/webhook/mail/send", request.BaseURL, region)
+	}
+
+	_, err := SetDataResidency(GetRequest("API_KEY", "/webhook/mail/send", ""), "mars")
+	assert.EqualError(t, err, `error: region can only be "ap", "eu", "global" or "us"`)
+}
+
+func TestSetDataResidencyHost(t *testing.T) {
+	request := GetRequest("API_KEY", "/webhook/mail/send", "")
+	custom, err := SetDataResidencyHost(request, "https://api.preview.cocoonmail.com/")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.preview.cocoonmail.com/webhook/mail/send", custom.BaseURL)
+
+	for _, host := range []string{"", "api.preview.cocoonmail.com", "http://api.preview.cocoonmail.com", "https://", "https://api.preview.cocoonmail.com?x=1"} {
+		unchanged, err := SetDataResidencyHost(request, host)
+		assert.NotNil(t, err, "host %q should be rejected", host)
+		assert.Equal(t, request.BaseURL, unchanged.BaseURL)
+	}
+}
+
+func TestSetDataResidencyCaseInsensitive(t *testing.T) {
+	request := GetRequest("API_KEY", "/webhook/mail/send", "")
+	for _, region := range []string{"EU", " eu ", "Eu"} {
+		regional, err := SetDataResidency(request, region)
+		assert.Nil(t, err, "region %q", region)
+		assert.Equal(t, "https://api.eu.cocoonmail.com/webhook/mail/send", regional.BaseURL, "region %q", region)
+	}
+	global, err := SetDataResidency(request, "Global")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://webhook.cocoonmail.com/webhook/mail/send", global.BaseURL)
+
+	_, err = SetDataResidency(request, "Mars")
+	assert.EqualError(t, err, `error: region can only be "ap", "eu", "global" or "us"`)
+}
+
+func TestSubuserHeader(t *testing.T) {
+	request := GetRequestSubuser("API_KEY", "/webhook/mail/send", "", " acme ")
+	assert.Equal(t, "acme", request.Headers["On-Behalf-Of"])
+	request = GetRequestSubuser("API_KEY", "/webhook/mail/send", "", "  ")
+	_, present := request.Headers["On-Behalf-Of"]
+	assert.False(t, present, "a blank subuser should not set the header")
+
+	capture := useCaptureTransport(t)
+	client := NewSendClient("API_KEY")
+	client.SetSubuser(" acme ")
+	_, err := client.Send(mail.NewMailSendRequest())
+	assert.Nil(t, err)
+	assert.Equal(t, "acme", capture.requests[0].Header.Get("On-Behalf-Of"))
+
+	client.SetSubuser("")
+	_, err = client.Send(mail.NewMailSendRequest())
+	assert.Nil(t, err)
+	_, present = capture.requests[1].Header["On-Behalf-Of"]
+	assert.False(t, present, "clearing the subuser should remove the header")
+}
+
+func TestSendIdempotencyKey(t *testing.T) {
+	var keys []string
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+
+	client := NewSendClient("API_KEY", WithRetry(2, time.Millisecond))
+	client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+	key := mail.GenerateIdempotencyKey()
+	_, err := client.Send(mail.NewMailSendRequest().SetIdempotencyKey(key))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{key, key}, keys, "the key should be sent on every attempt")
+	_, present := client.Headers["Idempotency-Key"]
+	assert.False(t, present, "the client headers should not be mutated")
+}
+
+func TestSendChunksIdempotencyKey(t *testing.T) {
+	capture := useCaptureTransport(t)
+	client := NewSendClient("API_KEY")
+	email := mail.NewMailSendRequest().SetIdempotencyKey("order-42").
+		AddRecipient(mail.NewMailRecipient("", "a@example.com"), mail.NewMailRecipient("", "b@example.com"))
+	for _, chunk := range email.ChunkByRecipients(1) {
+		_, err := client.Send(chunk)
+		assert.Nil(t, err)
+	}
+	if assert.Len(t, capture.requests, 2) {
+		first := capture.requests[0].Header.Get("Idempotency-Key")
+		second := capture.requests[1].Header.Get("Idempotency-Key")
+		assert.NotEmpty(t, first)
+		assert.NotEqual(t, first, second, "each chunk should be sent with its own key")
+	}
+}
+
+func TestSetHeader(t *testing.T) {
+	capture := useCaptureTransport(t)
+	client := NewSendClient("API_KEY")
+	assert.Nil(t, client.SetHeader("x-trace-id", "abc123"))
+	assert.Nil(t, client.AddHeaders(map[string]string{"X-Tenant": "acme", "X-Region": "eu"}))
+
+	_, err := client.Send(mail.NewMailSendRequest())
+	assert.Nil(t, err)
+	assert.Equal(t, "abc123", capture.requests[0].Header.Get("X-Trace-Id"))
+	assert.Equal(t, "acme", capture.requests[0].Header.Get("X-Tenant"))
+	assert.Equal(t, "eu", capture.requests[0].Header.Get("X-Region"))
+}
+
+func TestSetHeaderReserved(t *testing.T) {
+	client := NewSendClient("API_KEY")
+	assert.NotNil(t, client.SetHeader("authorization", "Bearer other"))
+	assert.NotNil(t, client.SetHeader("Content-Type", "text/plain"))
+	assert.NotNil(t, client.AddHeaders(map[string]string{"X-Tenant": "acme", "Authorization": "Bearer other"}))
+
+	assert.Equal(t, "Bearer API_KEY", client.Headers["Authorization"])
+	_, present := client.Headers["Content-Type"]
+	assert.False(t, present)
+	_, present = client.Headers["X-Tenant"]
+	assert.False(t, present, "no header should be set when one is reserved")
+}
+
+func TestSendBatch(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight, count := 0, 0, 0
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		count++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, `{"message_id":"id"}`)
+	}))
+	defer fakeServer.Close()
+
+	client := NewSendClient("API_KEY")
+	client.BaseURL = fakeServer.URL + "/webhook/mail/send"
+	reqs := make([]*mail.MailSendRequest, 10)
+	for i := range reqs {
+		reqs[i] = mail.NewMailSendRequest()
+	}
+	results := client.SendBatch(context.Background(), reqs, 3)
+
+	assert.Len(t, results, 10)
+	for i, result := range results {
+		assert.Equal(t, i, result.Index)
+		assert.Nil(t, result.Err)
+		if assert.NotNil(t, result.Response) {
+			assert.Equal(t, "id", result.Response.MessageID)
+		}
+	}
+	assert.Equal(t, 10, count)
+	assert.LessOrEqual(t, maxInFlight, 3, "no more than 3 sends should be in flight")
+	assert.Greater(t, maxInFlight, 1, "sends should run concurrently")
+}
+
+func TestSendBatchCanceled(t *testing.T) {
+	capture := useCaptureTransport(t)
+	client := NewSendClient("API_KEY")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := client.SendBatch(ctx, []*mail.MailSendRequest{mail.NewMailSendRequest(), mail.NewMailSendRequest()}, 0)
+	for _, result := range results {
+		assert.True(t, errors.Is(result.Err, context.Canceled))
+		assert.Nil(t, result.Response)
+	}
+	assert.Empty(t, capture.requests)
+}
+
+func TestSetDryRun(t *testing.T) {
+	capture := useCaptureTransport(t)
+	client := NewSendClient("API_KEY")
+	client.SetDryRun(true)
+	email := mail.NewMailSendRequest().AddRecipient(mail.NewMailRecipient("Jane", "jane@example.com")).SetSubject("Hi")
+
+	response, err := client.Send(email)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, string(mail.GetRequestBody(email)), response.Body)
+	assert.Nil(t, client.Warm(context.Background()))
+	assert.Empty(t, capture.requests, "a dry run should not make any request")
+
+	client.SetDryRun(false)
+	_, err = client.Send(email)
+	assert.Nil(t, err)
+	assert.Len(t, capture.requests, 1)
+}
+
+func TestWithAttachmentMIMEPolicy(t *testing.T) {
+	capture := useCaptureTransport(t)
+	email := mail.NewMailSendRequest().
+		AddRecipient(mail.NewMailRecipient("Jane", "jane@example.com")).
+		AddAttachment(mail.NewMailAttachment("invoice.pdf", "application/pdf", "JVBE")).
+		AddAttachment(mail.NewMailAttachment("setup.exe", "application/x-msdownload", "TVo="))
+
+	_, err := NewSendClient("API_KEY").Send(email)
+	if assert.NotNil(t, err, "executables should be denied by default") {
+		assert.Contains(t, err.Error(), `attachments[1] "setup.exe"`)
+	}
+	_, err = NewSendClient("API_KEY", WithAttachmentMIMEPolicy([]string{"application/pdf"}, nil)).Send(email)
+	assert.NotNil(t, err, "an allow list should still deny executables")
+	_, err = NewSendClient("API_KEY", WithAttachmentMIMEPolicy([]string{"application/x-msdownload"}, []string{"application/pdf"})).Send(email)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `attachments[0] "invoice.pdf": content type "application/pdf" is blocked`)
+	}
+	assert.Empty(t, capture.requests, "rejected emails should not be sent")
+
+	_, err = NewSendClient("API_KEY", WithAttachmentMIMEPolicy([]string{"application/x-msdownload", "application/pdf"}, nil)).Send(email)
+	assert.Nil(t, err, "explicitly allowed types should be sent")
+	assert.Len(t, capture.requests, 1)
+}
+
+func TestSetDryRunGzip(t *testing.T) {
+	capture := useCaptureTransport(t)
+	client := NewSendClient("API_KEY")
+	assert.Nil(t, client.SetHeader("Content-Encoding", "gzip"))
+	client.SetDryRun(true)
+	email := mail.NewMailSendRequest().AddRecipient(mail.NewMailRecipient("Jane", "jane@example.com")).SetSubject("Hi")
+
+	response, err := client.Send(email)
+	assert.Nil(t, err)
+	assert.Equal(t, string(mail.GetRequestBody(email)), response.Body, "a dry run should echo the JSON, not the gzipped body")
+	assert.Empty(t, capture.requests)
+}
+
+func TestListsClient(t *testing.T) {
+	type call struct{ method, path, body string }
+	var calls []call
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		calls = append(calls, call{r.Method, r.URL.Path, string(body)})
+		assert.Equal(t, "Bearer API_KEY", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/webhook/lists":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":"list-1","name":"Newsletter","contact_count":0}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/webhook/lists/list-1":
+			fmt.Fprint(w, `{"id":"list-1","name":"Newsletter","contact_count":42}`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/webhook/lists/list-1":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"not found"}`)
+		}
+	}))
+	defer fakeServer.Close()
+
+	client := NewListsClient("API_KEY")
+	assert.Equal(t, "https://webhook.cocoonmail.com/webhook/lists", client.client.BaseURL)
+	regional := NewListsClient("API_KEY")
+	assert.Nil(t, regional.SetDataResidency("eu"))
+	assert.Equal(t, "https://api.eu.cocoonmail.com/webhook/lists", regional.client.BaseURL)
+	assert.NotNil(t, regional.SetDataResidency("mars"))
+	client.client.BaseURL = fakeServer.URL + "/webhook/lists"
+	ctx := context.Background()
+
+	list, err := client.CreateList(ctx, "Newsletter")
+	if assert.Nil(t, err) {
+		assert.Equal(t, &List{ID: "list-1", Name: "Newsletter"}, list)
+	}
+	list, err = client.GetList(ctx, "list-1")
+	if assert.Nil(t, err) {
+		assert.Equal(t, 42, list.ContactCount)
+	}
+	assert.Nil(t, client.DeleteList(ctx, "list-1"))
+
+	assert.Equal(t, []call{
+		{http.MethodPost, "/webhook/lists", `{"name":"Newsletter"}`},
+		{http.MethodGet, "/webhook/lists/list-1", ""},
+		{http.MethodDelete, "/webhook/lists/list-1", ""},
+	}, calls)
+
+	_, err = client.GetList(ctx, "missing")
+	var restErr *rest.RestError
+	if assert.True(t, errors.As(err, &restErr)) {
+		assert.Equal(t, http.StatusNotFound, restErr.Response.StatusCode)
+	}
+}
+
+func TestSuppressionsClient(t *testing.T) {
+	var methods, bodies []string
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		methods = append(methods, r.Method)
+		bodies = append(bodies, string(body))
+		assert.Equal(t, "/webhook/suppressions", r.URL.Path)
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, `[{"email":"bounced@example.com","reason":"bounced","created_at":"2030-01-02T15:04:05Z"},{"email":"spam@example.com","reason":"complaint","created_at":"2030-01-03T00:00:00Z"}]`)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer fakeServer.Close()
+
+	client := NewSuppressionsClient("API_KEY")
+	client.client.BaseURL = fakeServer.URL + "/webhook/suppressions"
+
+	suppressions, err := client.GetSuppressions(context.Background())
+	if assert.Nil(t, err) && assert.Len(t, suppressions, 2) {
+		assert.Equal(t, "bounced@example.com", suppressions[0].Email)
+		assert.Equal(t, "bounced", suppressions[0].Reason)
+		assert.True(t, time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC).Equal(suppressions[0].CreatedAt))
+		assert.Equal(t, "complaint", suppressions[1].Reason)
+	}
+
+	assert.Nil(t, client.AddSuppression(context.Background(), "jane@example.com", "manual"))
+	assert.Equal(t, []string{http.MethodGet, http.MethodPost}, methods)
+	assert.JSONEq(t, `{"email":"jane@example.com","reason":"manual"}`, bodies[1])
+}
+
+func TestTemplatesClient(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		if r.URL.Path != "/webhook/templates/welcome" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"id":"welcome","name":"Welcome","required_variables":["first_name"]}`)
+	}))
+	defer fakeServer.Close()
+
+	client := NewTemplatesClient("API_KEY")
+	client.client.BaseURL = fakeServer.URL + "/webhook/templates"
+	template, err := client.GetTemplate(context.Background(), "welcome")
+	if assert.Nil(t, err) {
+		assert.Equal(t, &mail.Template{ID: "welcome", Name: "Welcome", RequiredVariables: []string{"first_name"}}, template)
+	}
+
+	email := NewSendClient("API_KEY").NewFromTemplate("welcome", func(m *mail.MailSendRequest) {
+		m.AddRecipient(mail.NewMailRecipient("Jane", "jane@example.com"))
+	})
+	assert.NotNil(t, email.ValidateAgainstTemplate(template), "first_name is missing")
+
+	_, err = client.GetTemplate(context.Background(), "missing")
+	assert.NotNil(t, err)
+}
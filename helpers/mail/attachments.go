@@ -0,0 +1,194 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// NewMailAttachmentFromFile reads the file at path into a base64 attachment.
+// The filename is the base name of path, passed through SanitizeFilename,
+// and the content type is inferred from its extension, falling back to
+// application/octet-stream.
+func NewMailAttachmentFromFile(path string) (*MailAttachment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading attachment %q: %w", path, err)
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	a := NewMailAttachment(filepath.Base(path), contentType, base64.StdEncoding.EncodeToString(data))
+	a.SanitizeFilename()
+	return a, nil
+}
+
+// SanitizeFilename makes Filename safe for mail clients and file systems:
+// directory components, with either slash, are stripped, control characters
+// are removed and the characters reserved on Windows (<>:"|?* and the
+// slashes) are replaced with '_'. A name left empty becomes "attachment".
+func (a *MailAttachment) SanitizeFilename() {
+	name := a.Filename
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r):
+			return -1
+		case strings.ContainsRune(`<>:"/\|?*`, r):
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." {
+		name = "attachment"
+	}
+	a.Filename = name
+}
+
+// DetectContentType sets ContentType by sniffing the first 512 decoded bytes
+// of Data with http.DetectContentType. Use it for attachments built without
+// a known content type.
+func (a *MailAttachment) DetectContentType() error {
+	data, err := base64.StdEncoding.DecodeString(a.Data)
+	if err != nil {
+		return fmt.Errorf("attachment %q data is not valid base64: %w", a.Filename, err)
+	}
+	if len(data) > 512 {
+		data = data[:512]
+	}
+	a.ContentType = http.DetectContentType(data)
+	return nil
+}
+
+// NewInlineImage returns an inline attachment with base64 data that HTML
+// content embeds with <img src="cid:...">, cid being its content ID.
+func NewInlineImage(filename, contentType, data, cid string) *MailAttachment {
+	a := NewMailAttachment(filename, contentType, data)
+	a.ContentID = cid
+	a.Disposition = DispositionInline
+	return a
+}
+
+// Fetch downloads RemoteLink with client, or http.DefaultClient if nil, and
+// returns it as an inline attachment, for recipients whose mail servers
+// don't follow remote links. The filename is the last element of the URL
+// path and the content type is the one of the response. Bodies over
+// maxBytes and non-2xx responses are errors.
+func (a *MailAttachmentRemote) Fetch(ctx context.Context, client *http.Client, maxBytes int64) (*MailAttachment, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u, err := url.Parse(a.RemoteLink)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.RemoteLink, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close() // nolint
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("fetching attachment %q: %s", a.RemoteLink, res.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetching attachment %q: %w", a.RemoteLink, err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("fetching attachment %q: larger than %d bytes", a.RemoteLink, maxBytes)
+	}
+
+	filename := path.Base(u.Path)
+	if filename == "/" || filename == "." {
+		filename = "attachment"
+	}
+	contentType := res.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	attachment := NewMailAttachment(filename, contentType, base64.StdEncoding.EncodeToString(data))
+	attachment.SanitizeFilename()
+	return attachment, nil
+}
+
+// IsDataURI reports whether RemoteLink is a data: URI rather than a link
+func (a *MailAttachmentRemote) IsDataURI() bool {
+	return len(a.RemoteLink) >= 5 && strings.EqualFold(a.RemoteLink[:5], "data:")
+}
+
+// ToAttachment converts a RemoteLink holding a data: URI, such as
+// "data:image/png;base64,iVBORw0...", into an inline attachment with the
+// URI's media type, "text/plain" if it has none. The filename is
+// "attachment" with the extension of the media type in dataURIExtensions,
+// or no extension for other types.
+func (a *MailAttachmentRemote) ToAttachment() (*MailAttachment, error) {
+	if !a.IsDataURI() {
+		return nil, fmt.Errorf("remote link %q is not a data URI", a.RemoteLink)
+	}
+	header, payload, found := strings.Cut(a.RemoteLink[5:], ",")
+	if !found {
+		return nil, fmt.Errorf("data URI has no payload")
+	}
+	mediaType, isBase64 := header, false
+	if strings.HasSuffix(strings.ToLower(header), ";base64") {
+		mediaType, isBase64 = header[:len(header)-len(";base64")], true
+	}
+	if mediaType == "" || strings.HasPrefix(mediaType, ";") {
+		mediaType = "text/plain" + mediaType
+	}
+	baseType, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return nil, fmt.Errorf("data URI media type %q: %w", mediaType, err)
+	}
+
+	var data []byte
+	if isBase64 {
+		data, err = base64.StdEncoding.DecodeString(payload)
+	} else {
+		var unescaped string
+		unescaped, err = url.PathUnescape(payload)
+		data = []byte(unescaped)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("data URI payload: %w", err)
+	}
+
+	filename := "attachment" + dataURIExtensions[baseType]
+	return NewMailAttachment(filename, mediaType, base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// dataURIExtensions maps the media types ToAttachment names files after to
+// their extension. It is fixed rather than read from the system MIME
+// tables, so the filename does not depend on the host.
+var dataURIExtensions = map[string]string{
+	"application/json": ".json",
+	"application/pdf":  ".pdf",
+	"application/zip":  ".zip",
+	"image/gif":        ".gif",
+	"image/jpeg":       ".jpg",
+	"image/png":        ".png",
+	"image/svg+xml":    ".svg",
+	"image/webp":       ".webp",
+	"text/csv":         ".csv",
+	"text/html":        ".html",
+	"text/plain":       ".txt",
+}
@@ -0,0 +1,171 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMailAttachmentFromFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "invoice.pdf")
+	content := []byte("%PDF-1.4 test document")
+	assert.Nil(t, os.WriteFile(path, content, 0o600))
+
+	a, err := NewMailAttachmentFromFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "invoice.pdf", a.Filename)
+	assert.Equal(t, "application/pdf", a.ContentType)
+	decoded, err := base64.StdEncoding.DecodeString(a.Data)
+	assert.Nil(t, err)
+	assert.Equal(t, content, decoded)
+
+	unknown := filepath.Join(dir, "blob.unknownext")
+	assert.Nil(t, os.WriteFile(unknown, content, 0o600))
+	a, err = NewMailAttachmentFromFile(unknown)
+	assert.Nil(t, err)
+	assert.Equal(t, "application/octet-stream", a.ContentType)
+}
+
+func TestNewMailAttachmentFromMissingFile(t *testing.T) {
+	a, err := NewMailAttachmentFromFile(filepath.Join(t.TempDir(), "missing.pdf"))
+	assert.Nil(t, a)
+	assert.True(t, errors.Is(err, os.ErrNotExist), "the read error should be wrapped")
+	assert.Contains(t, err.Error(), "missing.pdf")
+}
+
+func TestDetectContentType(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01")
+	a := NewMailAttachment("pixel", "", base64.StdEncoding.EncodeToString(png))
+	assert.Nil(t, a.DetectContentType())
+	assert.Equal(t, "image/png", a.ContentType)
+
+	pdf := []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n1 0 obj\n")
+	a = NewMailAttachment("doc", "", base64.StdEncoding.EncodeToString(pdf))
+	assert.Nil(t, a.DetectContentType())
+	assert.Equal(t, "application/pdf", a.ContentType)
+
+	a = NewMailAttachment("raw", "", "not base64!")
+	assert.NotNil(t, a.DetectContentType())
+	assert.Empty(t, a.ContentType)
+}
+
+func TestNewInlineImage(t *testing.T) {
+	img := NewInlineImage("logo.png", "image/png", "iVBORw0KGgo=", "logo")
+	m := validRequest().SetHTMLContent(`<img src="cid:logo">`).AddAttachment(img)
+	assert.Nil(t, m.Validate())
+
+	var body struct {
+		Attachments []map[string]string `json:"attachments"`
+	}
+	assert.Nil(t, json.Unmarshal(GetRequestBody(m), &body))
+	assert.Equal(t, []map[string]string{{
+		"filename":    "logo.png",
+		"contentType": "image/png",
+		"data":        "iVBORw0KGgo=",
+		"content_id":  "logo",
+		"disposition": "inline",
+	}}, body.Attachments)
+
+	img.ContentID = ""
+	err := m.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `attachments[0] "logo.png": inline attachment requires a content ID`)
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	for input, want := range map[string]string{
+		"../../etc/passwd":         "passwd",
+		`C:\Users\jane\report.pdf`: "report.pdf",
+		"report\n2024.pdf":         "report2024.pdf",
+		"bell\a.txt":               "bell.txt",
+		`what?"*|<>:.txt`:          "what_______.txt",
+		"résumé.pdf":               "résumé.pdf",
+		"../":                      "attachment",
+		"..":                       "attachment",
+		"\t":                       "attachment",
+	} {
+		a := NewMailAttachment(input, "text/plain", "")
+		a.SanitizeFilename()
+		assert.Equal(t, want, a.Filename, "filename %q", input)
+	}
+}
+
+func TestMailAttachmentRemoteFetch(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/files/terms.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			fmt.Fprint(w, "%PDF-1.4")
+		case "/files/big.bin":
+			w.Write(make([]byte, 2048)) // nolint
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer fakeServer.Close()
+
+	a, err := NewMailAttachmentRemote(fakeServer.URL+"/files/terms.pdf").Fetch(context.Background(), nil, 1024)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "terms.pdf", a.Filename)
+		assert.Equal(t, "application/pdf", a.ContentType)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("%PDF-1.4")), a.Data)
+	}
+
+	_, err = NewMailAttachmentRemote(fakeServer.URL+"/files/big.bin").Fetch(context.Background(), fakeServer.Client(), 1024)
+	if assert.NotNil(t, err, "a body over the cap should be rejected") {
+		assert.Contains(t, err.Error(), "larger than 1024 bytes")
+	}
+
+	_, err = NewMailAttachmentRemote(fakeServer.URL+"/missing").Fetch(context.Background(), nil, 1024)
+	assert.NotNil(t, err, "a 404 should be an error")
+}
+
+func TestMailAttachmentRemoteToAttachment(t *testing.T) {
+	png := "iVBORw0KGgoAAAANSUhEUg=="
+	remote := NewMailAttachmentRemote("data:image/png;base64," + png)
+	assert.True(t, remote.IsDataURI())
+	a, err := remote.ToAttachment()
+	if assert.Nil(t, err) {
+		assert.Equal(t, "image/png", a.ContentType)
+		assert.Equal(t, png, a.Data)
+		assert.Equal(t, "attachment.png", a.Filename)
+	}
+
+	a, err = NewMailAttachmentRemote("data:,Hello%2C%20World").ToAttachment()
+	if assert.Nil(t, err) {
+		assert.Equal(t, "text/plain", a.ContentType)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("Hello, World")), a.Data)
+		assert.Equal(t, "attachment.txt", a.Filename)
+	}
+	for link, want := range map[string]string{
+		"data:IMAGE/JPEG;base64,aGk=":           "attachment.jpg",
+		"data:text/csv;charset=utf-8,a%2Cb":     "attachment.csv",
+		"data:application/x-custom;base64,aGk=": "attachment",
+	} {
+		a, err := NewMailAttachmentRemote(link).ToAttachment()
+		if assert.Nil(t, err, "link %q", link) {
+			assert.Equal(t, want, a.Filename, "link %q", link)
+		}
+	}
+
+	for _, link := range []string{
+		"data:image/png;base64,iVBORw0KGgoAAAANSUhEU", // truncated
+		"data:image/png;base64",                       // no payload
+		"https://cdn.example.com/logo.png",            // not a data URI
+	} {
+		_, err := NewMailAttachmentRemote(link).ToAttachment()
+		assert.NotNil(t, err, "link %q should be rejected", link)
+	}
+	assert.False(t, NewMailAttachmentRemote("https://cdn.example.com/logo.png").IsDataURI())
+}
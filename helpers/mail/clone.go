@@ -0,0 +1,132 @@
+package mail
+
+// Clone returns a deep copy of the request: its recipients, with their
+// attributes, substitutions, lists and tags, attachments, custom parameters and Reply-To
+// lists are all copied, so changing the clone never affects m. Values
+// stored in attribute, substitution and custom parameter maps are copied
+// shallowly. The idempotency key is not copied: the clone is a new send, so
+// set its own key with SetIdempotencyKey if needed.
+func (m *MailSendRequest) Clone() *MailSendRequest {
+	c := *m
+	c.idempotencyKey = ""
+	c.To = cloneRecipients(m.To)
+	c.Cc = cloneRecipients(m.Cc)
+	c.Bcc = cloneRecipients(m.Bcc)
+	if m.Attachments != nil {
+		c.Attachments = make([]*MailAttachment, len(m.Attachments))
+		for i, a := range m.Attachments {
+			if a != nil {
+				copied := *a
+				c.Attachments[i] = &copied
+			}
+		}
+	}
+	if m.AttachmentsRemote != nil {
+		c.AttachmentsRemote = make([]*MailAttachmentRemote, len(m.AttachmentsRemote))
+		for i, a := range m.AttachmentsRemote {
+			if a != nil {
+				copied := *a
+				c.AttachmentsRemote[i] = &copied
+			}
+		}
+	}
+	c.CustomParameter = cloneMap(m.CustomParameter)
+	if m.ReplyToByList != nil {
+		c.ReplyToByList = make(map[string]string, len(m.ReplyToByList))
+		for k, v := range m.ReplyToByList {
+			c.ReplyToByList[k] = v
+		}
+	}
+	return &c
+}
+
+// redactedData replaces attachment data and other redacted values in a
+// Redacted request
+const redactedData = "[REDACTED]"
+
+// Redacted returns a clone that is safe to log: recipient and Reply-To
+// addresses are masked, e.g. "a***@x.com", as are recipient names, e.g.
+// "J***". Recipient street addresses and postal codes, the values of
+// recipient attributes and substitutions, and attachment data are replaced
+// by a placeholder. Other fields, such as the subject, the recipient city or
+// company and custom parameters, are kept as is.
+func (m *MailSendRequest) Redacted() *MailSendRequest {
+	c := m.Clone()
+	for _, recipients := range [][]*MailRecipient{c.To, c.Cc, c.Bcc} {
+		for _, r := range recipients {
+			if r != nil {
+				redactRecipient(r)
+			}
+		}
+	}
+	if c.ReplyTo != "" {
+		c.ReplyTo = maskEmail(c.ReplyTo)
+	}
+	for list, replyTo := range c.ReplyToByList {
+		c.ReplyToByList[list] = maskEmail(replyTo)
+	}
+	for _, a := range c.Attachments {
+		if a != nil && a.Data != "" {
+			a.Data = redactedData
+		}
+	}
+	return c
+}
+
+// redactRecipient masks the personal data of a cloned recipient in place
+func redactRecipient(r *MailRecipient) {
+	if r.Email != "" {
+		r.Email = maskEmail(r.Email)
+	}
+	r.Name = maskName(r.Name)
+	r.FirstName = maskName(r.FirstName)
+	r.MiddleName = maskName(r.MiddleName)
+	r.LastName = maskName(r.LastName)
+	for _, field := range []*string{&r.Address1, &r.Address2, &r.PostalCode} {
+		if *field != "" {
+			*field = redactedData
+		}
+	}
+	for key := range r.Attributes {
+		r.Attributes[key] = redactedData
+	}
+	for key := range r.Substitutions {
+		r.Substitutions[key] = redactedData
+	}
+}
+
+// cloneRecipients deep copies a recipient slice, keeping nil entries
+func cloneRecipients(recipients []*MailRecipient) []*MailRecipient {
+	if recipients == nil {
+		return nil
+	}
+	out := make([]*MailRecipient, len(recipients))
+	for i, r := range recipients {
+		if r == nil {
+			continue
+		}
+		copied := *r
+		copied.Attributes = cloneMap(r.Attributes)
+		copied.Substitutions = cloneMap(r.Substitutions)
+		if r.Lists != nil {
+			copied.Lists = append([]string{}, r.Lists...)
+		}
+		if r.Tags != nil {
+			copied.Tags = append([]string{}, r.Tags...)
+		}
+		out[i] = &copied
+	}
+	return out
+}
+
+// cloneMap copies the top level of m, keeping nil as nil
+func cloneMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package mail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClone(t *testing.T) {
+	original := validRequest().
+		AddBcc(NewMailRecipient("Audit", "audit@example.com")).
+		AddAttachment(NewMailAttachment("a.txt", "text/plain", "aGk=")).
+		AddRemoteAttachment(NewMailAttachmentRemote("https://cdn.example.com/a.pdf")).
+		SetCustomParameter("plan", "pro")
+	_, err := original.SetReplyToForList("vip", "vip@example.com")
+	assert.Nil(t, err)
+	original.To[0].AddTag("vip").AddToList("list-1").SetAttribute("seats", 5)
+	want := GetRequestBody(original)
+
+	clone := original.Clone()
+	assert.Equal(t, original, clone)
+
+	clone.To[0].Email = "changed@example.com"
+	clone.To[0].AddTag("beta").AddToList("list-2").SetAttribute("seats", 6)
+	clone.AddRecipient(NewMailRecipient("New", "new@example.com"))
+	clone.Cc[0].Name = "Changed"
+	clone.Bcc = clone.Bcc[:0]
+	clone.Attachments[0].Data = "Ynll"
+	clone.AttachmentsRemote[0].RemoteLink = "https://cdn.example.com/b.pdf"
+	clone.SetCustomParameter("plan", "free")
+	clone.ReplyToByList["vip"] = "other@example.com"
+
+	assert.Equal(t, string(want), string(GetRequestBody(original)), "changing the clone should not affect the original")
+	assert.Equal(t, "vip@example.com", original.ReplyToByList["vip"])
+}
+
+func TestRedacted(t *testing.T) {
+	original := validRequest().
+		AddBcc(NewMailRecipient("Audit", "audit@example.com")).
+		AddAttachment(NewMailAttachment("a.txt", "text/plain", "c2VjcmV0")).
+		SetSubject("Invoice")
+	original.To[0].SetAttribute("plan", "pro").SetSubstitution("code", "SECRET-1")
+	original.To[0].Address1 = "1 Main Street"
+	original.To[0].City = "Springfield"
+	want := GetRequestBody(original)
+
+	redacted := original.Redacted()
+	assert.Equal(t, "j***@example.com", redacted.To[0].Email)
+	assert.Equal(t, "J***", redacted.To[0].Name)
+	assert.Equal(t, "[REDACTED]", redacted.To[0].Address1)
+	assert.Equal(t, "Springfield", redacted.To[0].City)
+	assert.Equal(t, map[string]interface{}{"plan": "[REDACTED]"}, redacted.To[0].Attributes)
+	assert.Equal(t, map[string]interface{}{"code": "[REDACTED]"}, redacted.To[0].Substitutions)
+	assert.NotContains(t, string(GetRequestBody(redacted)), "Jane")
+	assert.Equal(t, "j***@example.com", redacted.Cc[0].Email)
+	assert.Equal(t, "a***@example.com", redacted.Bcc[0].Email)
+	assert.Equal(t, "s***@example.com", redacted.ReplyTo)
+	assert.Equal(t, "[REDACTED]", redacted.Attachments[0].Data)
+	assert.Equal(t, "a.txt", redacted.Attachments[0].Filename)
+	assert.Equal(t, "Invoice", redacted.Subject)
+	assert.Equal(t, original.ScheduledAt, redacted.ScheduledAt)
+
+	assert.Equal(t, string(want), string(GetRequestBody(original)), "the original should not be redacted")
+}
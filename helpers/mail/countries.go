@@ -0,0 +1,131 @@
+package mail
+
+import (
+	"fmt"
+	"strings"
+)
+
+// countryCodes maps lowercase country names and common aliases to their
+// ISO 3166-1 alpha-2 code. It covers the countries most recipients are in;
+// extend it as needed.
+var countryCodes = map[string]string{
+	"argentina":                "AR",
+	"australia":                "AU",
+	"austria":                  "AT",
+	"belgium":                  "BE",
+	"brazil":                   "BR",
+	"canada":                   "CA",
+	"chile":                    "CL",
+	"china":                    "CN",
+	"colombia":                 "CO",
+	"czech republic":           "CZ",
+	"czechia":                  "CZ",
+	"denmark":                  "DK",
+	"egypt":                    "EG",
+	"finland":                  "FI",
+	"france":                   "FR",
+	"germany":                  "DE",
+	"greece":                   "GR",
+	"hong kong":                "HK",
+	"hungary":                  "HU",
+	"india":                    "IN",
+	"indonesia":                "ID",
+	"ireland":                  "IE",
+	"israel":                   "IL",
+	"italy":                    "IT",
+	"japan":                    "JP",
+	"kenya":                    "KE",
+	"malaysia":                 "MY",
+	"mexico":                   "MX",
+	"netherlands":              "NL",
+	"the netherlands":          "NL",
+	"new zealand":              "NZ",
+	"nigeria":                  "NG",
+	"norway":                   "NO",
+	"pakistan":                 "PK",
+	"peru":                     "PE",
+	"philippines":              "PH",
+	"poland":                   "PL",
+	"portugal":                 "PT",
+	"romania":                  "RO",
+	"saudi arabia":             "SA",
+	"singapore":                "SG",
+	"south africa":             "ZA",
+	"south korea":              "KR",
+	"spain":                    "ES",
+	"sweden":                   "SE",
+	"switzerland":              "CH",
+	"taiwan":                   "TW",
+	"thailand":                 "TH",
+	"turkey":                   "TR",
+	"ukraine":                  "UA",
+	"united arab emirates":     "AE",
+	"uae":                      "AE",
+	"united kingdom":           "GB",
+	"uk":                       "GB",
+	"great britain":            "GB",
+	"united states":            "US",
+	"united states of america": "US",
+	"usa":                      "US",
+	"vietnam":                  "VN",
+}
+
+// isoCountryCodes lists every officially assigned ISO 3166-1 alpha-2 code,
+// grouped by first letter
+const isoCountryCodes = `
+AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ
+BA BB BD BE BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY BZ
+CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ
+DE DJ DK DM DO DZ
+EC EE EG EH ER ES ET
+FI FJ FK FM FO FR
+GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY
+HK HM HN HR HT HU
+ID IE IL IM IN IO IQ IR IS IT
+JE JM JO JP
+KE KG KH KI KM KN KP KR KW KY KZ
+LA LB LC LI LK LR LS LT LU LV LY
+MA MC MD ME MF MG MH MK ML MM MN MO MP MQ MR MS MT MU MV MW MX MY MZ
+NA NC NE NF NG NI NL NO NP NR NU NZ
+OM
+PA PE PF PG PH PK PL PM PN PR PS PT PW PY
+QA
+RE RO RS RU RW
+SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS ST SV SX SY SZ
+TC TD TF TG TH TJ TK TL TM TN TO TR TT TV TW TZ
+UA UG UM US UY UZ
+VA VC VE VG VI VN VU
+WF WS
+YE YT
+ZA ZM ZW
+`
+
+// knownCountryCodes holds the codes of isoCountryCodes
+var knownCountryCodes = func() map[string]bool {
+	fields := strings.Fields(isoCountryCodes)
+	codes := make(map[string]bool, len(fields))
+	for _, code := range fields {
+		codes[code] = true
+	}
+	return codes
+}()
+
+// NormalizeCountry replaces Country, a country name such as "United States"
+// or a two-letter code such as "us", with its uppercase ISO 3166-1 alpha-2
+// code. An empty Country is left as is; an unrecognized one is an error and
+// is left unchanged.
+func (r *MailRecipient) NormalizeCountry() error {
+	country := strings.Join(strings.Fields(strings.ToLower(r.Country)), " ")
+	if country == "" {
+		return nil
+	}
+	if code, ok := countryCodes[country]; ok {
+		r.Country = code
+		return nil
+	}
+	if code := strings.ToUpper(country); knownCountryCodes[code] {
+		r.Country = code
+		return nil
+	}
+	return fmt.Errorf("country %q: not a recognized country name or ISO 3166-1 alpha-2 code", r.Country)
+}
@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeCountry(t *testing.T) {
+	for input, want := range map[string]string{
+		"United States":      "US",
+		"  united   states ": "US",
+		"us":                 "US",
+		"GB":                 "GB",
+		"uk":                 "GB",
+		"Germany":            "DE",
+		"lu":                 "LU",
+		"BG":                 "BG",
+		"is":                 "IS",
+		"Zw":                 "ZW",
+		"":                   "",
+	} {
+		r := NewMailRecipient("Jane", "jane@example.com").SetCountry(input)
+		assert.Nil(t, r.NormalizeCountry(), "country %q", input)
+		assert.Equal(t, want, r.Country, "country %q", input)
+	}
+
+	r := NewMailRecipient("Jane", "jane@example.com").SetCountry("Atlantis")
+	assert.NotNil(t, r.NormalizeCountry())
+	assert.Equal(t, "Atlantis", r.Country, "an unrecognized country should be left unchanged")
+}
+
+func TestKnownCountryCodes(t *testing.T) {
+	assert.Len(t, knownCountryCodes, 249)
+	for _, code := range countryCodes {
+		assert.True(t, knownCountryCodes[code], "code %s of the name table should be known", code)
+	}
+	for _, country := range []string{"korea", "XX", "EU"} {
+		r := NewMailRecipient("Jane", "jane@example.com").SetCountry(country)
+		assert.NotNil(t, r.NormalizeCountry(), "country %q should not be recognized", country)
+	}
+}
@@ -0,0 +1,121 @@
+package mail
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// DiffRequests returns a human-readable diff of the marshaled fields of two
+// requests, one changed field per line, or "" when they are the same.
+// Attachment data is summarized by its size rather than dumped.
+func DiffRequests(a, b *MailSendRequest) string {
+	fa, fb := flattenRequest(a), flattenRequest(b)
+
+	keys := make([]string, 0, len(fa)+len(fb))
+	for k := range fa {
+		keys = append(keys, k)
+	}
+	for k := range fb {
+		if _, ok := fa[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		va, okA := fa[k]
+		vb, okB := fb[k]
+		if okA && okB && va == vb {
+			continue
+		}
+		if !okA {
+			va = "(absent)"
+		}
+		if !okB {
+			vb = "(absent)"
+		}
+		fmt.Fprintf(&sb, "%s: %s => %s\n", k, va, vb)
+	}
+	return sb.String()
+}
+
+// DiffRequestsRedacted is like DiffRequests but diffs the Redacted copies of
+// the requests, so the result is safe to log and masks exactly what
+// Redacted does.
+func DiffRequestsRedacted(a, b *MailSendRequest) string {
+	return DiffRequests(redactedOrNil(a), redactedOrNil(b))
+}
+
+// redactedOrNil returns m.Redacted(), or nil if m is nil
+func redactedOrNil(m *MailSendRequest) *MailSendRequest {
+	if m == nil {
+		return nil
+	}
+	return m.Redacted()
+}
+
+// flattenRequest maps the JSON path of every leaf value of the marshaled
+// request to its JSON-encoded value.
+func flattenRequest(m *MailSendRequest) map[string]string {
+	out := make(map[string]string)
+	if m == nil {
+		return out
+	}
+	var tree interface{}
+	if err := json.Unmarshal(GetRequestBody(m), &tree); err != nil {
+		return out
+	}
+	flattenValue("", tree, out)
+	return out
+}
+
+func flattenValue(path string, v interface{}, out map[string]string) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, child := range value {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			flattenValue(p, child, out)
+		}
+	case []interface{}:
+		for i, child := range value {
+			flattenValue(fmt.Sprintf("%s[%d]", path, i), child, out)
+		}
+	case string:
+		switch {
+		case strings.HasPrefix(path, "attachments[") && strings.HasSuffix(path, "].data"):
+			out[path] = fmt.Sprintf("<%d bytes>", attachmentSize(&MailAttachment{Data: value}))
+		default:
+			out[path] = fmt.Sprintf("%q", value)
+		}
+	default:
+		b, _ := json.Marshal(value)
+		out[path] = string(b)
+	}
+}
+
+// maskEmail keeps the first character of the local part and the domain,
+// e.g. "a***@x.com".
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return "***"
+	}
+	return maskName(email[:at]) + email[at:]
+}
+
+// maskName keeps the first character of s, e.g. "J***", or returns s if
+// it is empty
+func maskName(s string) string {
+	if s == "" {
+		return s
+	}
+	_, size := utf8.DecodeRuneInString(s)
+	return s[:size] + "***"
+}
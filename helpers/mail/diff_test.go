@@ -0,0 +1,57 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffRequests(t *testing.T) {
+	a := NewMailSendRequest().
+		AddRecipient(NewMailRecipient("Jane", "jane@example.com")).
+		AddAttachment(NewMailAttachment("a.txt", "text/plain", "aGVsbG8=")).
+		SetReplyTo("support@example.com")
+	b := NewMailSendRequest().
+		AddRecipient(NewMailRecipient("Jane", "jane@example.com"), NewMailRecipient("John", "john@example.com")).
+		AddAttachment(NewMailAttachment("a.txt", "text/plain", "aGVsbG8gd29ybGQ=")).
+		SetAllowOpenTracking(true)
+
+	assert.Equal(t, "", DiffRequests(a, a))
+
+	diff := DiffRequests(a, b)
+	assert.Equal(t, []string{
+		`allow_open_tracking: (absent) => true`,
+		`attachments[0].data: <5 bytes> => <11 bytes>`,
+		`reply_to: "support@example.com" => (absent)`,
+		`to[1].email: (absent) => "john@example.com"`,
+		`to[1].name: (absent) => "John"`,
+	}, strings.Split(strings.TrimSpace(diff), "\n"))
+	assert.NotContains(t, diff, "aGVsbG8", "attachment data should not be dumped")
+}
+
+func TestDiffRequestsRedacted(t *testing.T) {
+	a := NewMailSendRequest().AddRecipient(NewMailRecipient("Jane", "jane@example.com"))
+	b := NewMailSendRequest().AddRecipient(NewMailRecipient("Jane", "jim@example.org"))
+
+	diff := DiffRequestsRedacted(a, b)
+	assert.Equal(t, "to[0].email: \"j***@example.com\" => \"j***@example.org\"\n", diff)
+	assert.NotContains(t, diff, "jane@")
+
+	a.To[0].SetAttribute("plan", "pro").Address1 = "1 Main Street"
+	b.To[0].SetAttribute("plan", "enterprise").Name = "Jim"
+	b.To[0].Address1 = "2 Side Street"
+	diff = DiffRequestsRedacted(a, b)
+	for _, clear := range []string{"jane@", "Jim", "Main Street", "Side Street", "enterprise"} {
+		assert.NotContains(t, diff, clear, "the redacted diff should mask what Redacted masks")
+	}
+	assert.Equal(t, DiffRequests(a.Redacted(), b.Redacted()), diff)
+	assert.Contains(t, DiffRequestsRedacted(nil, b), "(absent)")
+}
+
+func TestMaskEmail(t *testing.T) {
+	assert.Equal(t, "a***@x.com", maskEmail("alice@x.com"))
+	assert.Equal(t, "é***@x.com", maskEmail("éloïse@x.com"), "the first character should be kept whole")
+	assert.Equal(t, "***", maskEmail("not-an-email"))
+	assert.Equal(t, "***", maskEmail("@x.com"))
+}
@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"context"
+
+	"github.com/cocoonmail/cocoonmail-go/rest"
+)
+
+// BodySender sends an already marshaled mail send body with its
+// idempotency key, which is empty if none was set.
+// *cocoonmail.Client implements it.
+type BodySender interface {
+	SendBody(ctx context.Context, body []byte, idempotencyKey string) (*rest.Response, error)
+}
+
+// FrozenRequest is an immutable snapshot of a MailSendRequest, taken by Freeze.
+type FrozenRequest struct {
+	body           []byte
+	idempotencyKey string
+}
+
+// Freeze marshals the request into an immutable FrozenRequest. The snapshot
+// is a defensive copy: later changes to m, its recipients or attachments do
+// not affect it. Freeze after validation to hand a request to a sending layer.
+// It returns an error if the request cannot be marshaled, e.g. because a
+// custom parameter holds a channel: the frozen body could otherwise only be
+// nil, which Send would post as an empty request with no sign of the
+// failure.
+func (m *MailSendRequest) Freeze() (*FrozenRequest, error) {
+	body, err := MarshalRequest(m)
+	if err != nil {
+		return nil, err
+	}
+	return &FrozenRequest{body: body, idempotencyKey: m.idempotencyKey}, nil
+}
+
+// Bytes returns a copy of the marshaled request body
+func (f *FrozenRequest) Bytes() []byte {
+	return append([]byte(nil), f.body...)
+}
+
+// IdempotencyKey returns the idempotency key of the request when it was frozen
+func (f *FrozenRequest) IdempotencyKey() string {
+	return f.idempotencyKey
+}
+
+// Send sends the frozen request with the given client, along with its
+// idempotency key
+func (f *FrozenRequest) Send(ctx context.Context, client BodySender) (*rest.Response, error) {
+	return client.SendBody(ctx, f.Bytes(), f.idempotencyKey)
+}
@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cocoonmail/cocoonmail-go/rest"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingSender struct {
+	body []byte
+	key  string
+}
+
+func (s *recordingSender) SendBody(ctx context.Context, body []byte, idempotencyKey string) (*rest.Response, error) {
+	s.body = body
+	s.key = idempotencyKey
+	return &rest.Response{StatusCode: 202}, nil
+}
+
+func TestFreeze(t *testing.T) {
+	m := NewMailSendRequest().
+		AddRecipient(NewMailRecipient("Jane", "jane@example.com")).
+		SetReplyTo("support@example.com").
+		SetIdempotencyKey("order-42")
+	want := GetRequestBody(m)
+
+	frozen, err := m.Freeze()
+	assert.Nil(t, err)
+	m.SetReplyTo("changed@example.com").SetIdempotencyKey("changed")
+	m.To[0].Email = "changed@example.com"
+	m.AddRecipient(NewMailRecipient("John", "john@example.com"))
+	assert.Equal(t, want, frozen.Bytes(), "mutating the request should not change the frozen body")
+
+	b := frozen.Bytes()
+	b[0] = 'X'
+	assert.Equal(t, want, frozen.Bytes(), "mutating the returned bytes should not change the frozen body")
+
+	sender := &recordingSender{}
+	response, err := frozen.Send(context.Background(), sender)
+	assert.Nil(t, err)
+	assert.Equal(t, 202, response.StatusCode)
+	assert.Equal(t, want, sender.body)
+	assert.Equal(t, "order-42", sender.key, "the key should be sent with the frozen body")
+}
+
+func TestFreezeMarshalError(t *testing.T) {
+	m := NewMailSendRequest().SetCustomParameter("bad", make(chan int))
+	frozen, err := m.Freeze()
+	assert.NotNil(t, err, "a request that cannot be marshaled should not be frozen")
+	assert.Nil(t, frozen)
+}
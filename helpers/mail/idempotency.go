@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// SetIdempotencyKey sets the key sent as the Idempotency-Key header of the
+// send, so the API can drop duplicates caused by retries. The same key is
+// sent on every attempt. An empty key sends no header.
+func (m *MailSendRequest) SetIdempotencyKey(key string) *MailSendRequest {
+	m.idempotencyKey = key
+	return m
+}
+
+// IdempotencyKey returns the key set by SetIdempotencyKey
+func (m *MailSendRequest) IdempotencyKey() string {
+	return m.idempotencyKey
+}
+
+// partIdempotencyKey returns the key of the i-th request split from m, so
+// each part is deduplicated on its own: "<key>-1", "<key>-2" and so on. The
+// keys are stable, so splitting the same request again yields the same ones.
+func (m *MailSendRequest) partIdempotencyKey(i int) string {
+	if m.idempotencyKey == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s-%d", m.idempotencyKey, i+1)
+}
+
+// GenerateIdempotencyKey returns a random UUID (version 4) for use with SetIdempotencyKey
+func GenerateIdempotencyKey() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err) // crypto/rand does not fail on supported platforms
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
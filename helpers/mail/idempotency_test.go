@@ -0,0 +1,21 @@
+package mail
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateIdempotencyKey(t *testing.T) {
+	uuidV4 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	key := GenerateIdempotencyKey()
+	assert.Regexp(t, uuidV4, key)
+	assert.NotEqual(t, key, GenerateIdempotencyKey(), "keys should be random")
+}
+
+func TestSetIdempotencyKey(t *testing.T) {
+	m := NewMailSendRequest().SetIdempotencyKey("order-42")
+	assert.Equal(t, "order-42", m.IdempotencyKey())
+	assert.NotContains(t, string(GetRequestBody(m)), "order-42", "the key is a header, not part of the body")
+}
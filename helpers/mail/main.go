@@ -1,11 +1,19 @@
 package mail
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/mail"
+	"net/url"
+	"path"
 	"strings"
+	"time"
+
+	"golang.org/x/net/idna"
 )
 
 const (
@@ -16,12 +24,24 @@ const (
 	maxEmailLocalLength = 64
 	// Max email length must not exceed 320 characters.
 	maxEmailLength = maxEmailDomainLength + maxEmailLocalLength + 1
+	// Conversation IDs must not exceed 128 characters.
+	maxConversationIDLength = 128
+
+	// DefaultMaxTotalAttachmentBytes is the default limit on the decoded size
+	// of all inline attachments of a request, checked by Validate.
+	DefaultMaxTotalAttachmentBytes int64 = 25 * 1024 * 1024
+
+	// scheduleGracePeriod is how far in the past ScheduledAt may be, to
+	// allow for clock skew and the time taken to send.
+	scheduleGracePeriod = time.Minute
 )
 
 // MailSendRequest models the payload for Cocoonmail's send mail API
 type MailSendRequest struct {
 	TransactionalID          string                  `json:"transactional_id,omitempty"`
 	To                       []*MailRecipient        `json:"to,omitempty"`
+	Cc                       []*MailRecipient        `json:"cc,omitempty"`
+	Bcc                      []*MailRecipient        `json:"bcc,omitempty"`
 	ReplyTo                  string                  `json:"reply_to,omitempty"`
 	CustomParameter          map[string]interface{}  `json:"custom_parameter,omitempty"`
 	Attachments              []*MailAttachment       `json:"attachments,omitempty"`
@@ -36,6 +56,32 @@ type MailSendRequest struct {
 	EmailContent             string                  `json:"email_content,omitempty"`
 	Sender                   string                  `json:"sender,omitempty"`
 	Subject                  string                  `json:"subject,omitempty"`
+	HTMLContent              string                  `json:"html,omitempty"`
+	TextContent              string                  `json:"text,omitempty"`
+	// ConversationID groups related messages at the application level and
+	// is echoed back in webhook events. It is unrelated to RFC 5322
+	// threading (In-Reply-To/References), which mail clients use to
+	// thread messages in the inbox.
+	ConversationID string `json:"conversation_id,omitempty"`
+	// ContentLanguage is sent as the Content-Language header and declares
+	// the language the content is written in, for mail clients and screen
+	// readers. It is a BCP 47 tag such as "en" or "pt-BR".
+	ContentLanguage string `json:"content_language,omitempty"`
+	// ReplyToByList maps a list to the Reply-To used for its members. It is
+	// applied client-side by SplitByReplyToList and never sent to the API.
+	ReplyToByList map[string]string `json:"-"`
+
+	// maxTotalAttachmentBytes overrides DefaultMaxTotalAttachmentBytes when positive
+	maxTotalAttachmentBytes int64
+	// now returns the current time for Validate; nil means time.Now
+	now func() time.Time
+	// idempotencyKey is sent as the Idempotency-Key header, see SetIdempotencyKey
+	idempotencyKey string
+	// allowDuplicateAcrossLists turns off the duplicate check of Validate
+	allowDuplicateAcrossLists bool
+	// allowedContentTypes and blockedContentTypes are set by SetAttachmentContentTypePolicy
+	allowedContentTypes []string
+	blockedContentTypes []string
 }
 
 // MailRecipient encapsulates recipient details and attributes
@@ -46,12 +92,13 @@ type MailRecipient struct {
 	MiddleName      string                 `json:"middle_name,omitempty"`
 	LastName        string                 `json:"last_name,omitempty"`
 	Attributes      map[string]interface{} `json:"attributes,omitempty"`
+	Substitutions   map[string]interface{} `json:"substitutions,omitempty"` // for this send only, see SetSubstitution
 	Lists           []string               `json:"lists,omitempty"`
 	Tags            []string               `json:"tags,omitempty"`
 	Gender          string                 `json:"gender,omitempty"`
 	Age             int                    `json:"age,omitempty"`
-	Address1        string                 `json:"Address1,omitempty"`
-	Address2        string                 `json:"Address2,omitempty"`
+	Address1        string                 `json:"address1,omitempty"` // JSON stored as "Address1" still decodes, keys match case-insensitively
+	Address2        string                 `json:"address2,omitempty"`
 	City            string                 `json:"city,omitempty"`
 	State           string                 `json:"state,omitempty"`
 	Country         string                 `json:"country,omitempty"`
@@ -68,8 +115,18 @@ type MailAttachment struct {
 	Filename    string `json:"filename,omitempty"`
 	ContentType string `json:"contentType,omitempty"`
 	Data        string `json:"data,omitempty"`
+	// ContentID lets HTML content reference the attachment as "cid:<ContentID>"
+	ContentID string `json:"content_id,omitempty"`
+	// Disposition is DispositionInline or DispositionAttachment; empty means attachment
+	Disposition string `json:"disposition,omitempty"`
 }
 
+// Attachment dispositions
+const (
+	DispositionInline     = "inline"
+	DispositionAttachment = "attachment"
+)
+
 // MailAttachmentRemote is for attachments hosted externally
 type MailAttachmentRemote struct {
 	RemoteLink string `json:"remote_link,omitempty"`
@@ -79,6 +136,8 @@ type MailAttachmentRemote struct {
 func NewMailSendRequest() *MailSendRequest {
 	return &MailSendRequest{
 		To:                make([]*MailRecipient, 0),
+		Cc:                make([]*MailRecipient, 0),
+		Bcc:               make([]*MailRecipient, 0),
 		Attachments:       make([]*MailAttachment, 0),
 		AttachmentsRemote: make([]*MailAttachmentRemote, 0),
 		CustomParameter:   make(map[string]interface{}),
@@ -91,6 +150,70 @@ func (m *MailSendRequest) AddRecipient(recipients ...*MailRecipient) *MailSendRe
 	return m
 }
 
+// AddRecipientChecked appends recipients after checking each email with
+// ParseEmail. On the first invalid or missing recipient it returns an error
+// naming it, and none of the recipients are appended.
+func (m *MailSendRequest) AddRecipientChecked(recipients ...*MailRecipient) (*MailSendRequest, error) {
+	for i, r := range recipients {
+		if r == nil {
+			return m, fmt.Errorf("recipient %d: missing", i)
+		}
+		if _, err := ParseEmail(r.Email); err != nil {
+			return m, fmt.Errorf("recipient %d %q: %w", i, r.Email, err)
+		}
+	}
+	return m.AddRecipient(recipients...), nil
+}
+
+// AddRecipientEmails parses each string with ParseEmail and appends the
+// recipients. On the first invalid address it returns an error naming it,
+// and none of the recipients are appended.
+func (m *MailSendRequest) AddRecipientEmails(emails ...string) (*MailSendRequest, error) {
+	recipients := make([]*MailRecipient, 0, len(emails))
+	for _, email := range emails {
+		r, err := ParseEmail(email)
+		if err != nil {
+			return m, fmt.Errorf("address %q: %w", email, err)
+		}
+		recipients = append(recipients, r)
+	}
+	return m.AddRecipient(recipients...), nil
+}
+
+// DedupeRecipients removes To recipients whose email, compared case-insensitively,
+// already appears earlier in To. Order and the first occurrence are kept.
+func (m *MailSendRequest) DedupeRecipients() *MailSendRequest {
+	seen := make(map[string]bool, len(m.To))
+	kept := m.To[:0]
+	for _, r := range m.To {
+		if r != nil {
+			key := strings.ToLower(r.Email)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		kept = append(kept, r)
+	}
+	for i := len(kept); i < len(m.To); i++ {
+		m.To[i] = nil
+	}
+	m.To = kept
+	return m
+}
+
+// AddCc appends one or more Cc recipients to the request
+func (m *MailSendRequest) AddCc(recipients ...*MailRecipient) *MailSendRequest {
+	m.Cc = append(m.Cc, recipients...)
+	return m
+}
+
+// AddBcc appends one or more Bcc recipients to the request
+func (m *MailSendRequest) AddBcc(recipients ...*MailRecipient) *MailSendRequest {
+	m.Bcc = append(m.Bcc, recipients...)
+	return m
+}
+
 // AddAttachment appends one or more file attachments
 func (m *MailSendRequest) AddAttachment(att ...*MailAttachment) *MailSendRequest {
 	m.Attachments = append(m.Attachments, att...)
@@ -103,12 +226,211 @@ func (m *MailSendRequest) AddRemoteAttachment(rem ...*MailAttachmentRemote) *Mai
 	return m
 }
 
+// DuplicateAttachments returns the filenames of inline attachments that are
+// also added as a remote attachment with the same base name. Such files are
+// delivered twice; this is a heuristic warning, not an error, so Validate
+// does not report them. Call it alongside Validate to warn about them.
+func (m *MailSendRequest) DuplicateAttachments() []string {
+	remote := remoteAttachmentNames(m.AttachmentsRemote)
+	var dups []string
+	for _, a := range m.Attachments {
+		if a != nil && a.Filename != "" && remote[a.Filename] {
+			dups = append(dups, a.Filename)
+		}
+	}
+	return dups
+}
+
+// DropInlineDuplicates removes inline attachments that duplicate a remote attachment
+func (m *MailSendRequest) DropInlineDuplicates() *MailSendRequest {
+	remote := remoteAttachmentNames(m.AttachmentsRemote)
+	kept := make([]*MailAttachment, 0, len(m.Attachments))
+	for _, a := range m.Attachments {
+		if a != nil && a.Filename != "" && remote[a.Filename] {
+			continue
+		}
+		kept = append(kept, a)
+	}
+	m.Attachments = kept
+	return m
+}
+
+// DropRemoteDuplicates removes remote attachments that duplicate an inline attachment
+func (m *MailSendRequest) DropRemoteDuplicates() *MailSendRequest {
+	inline := make(map[string]bool, len(m.Attachments))
+	for _, a := range m.Attachments {
+		if a != nil && a.Filename != "" {
+			inline[a.Filename] = true
+		}
+	}
+	kept := make([]*MailAttachmentRemote, 0, len(m.AttachmentsRemote))
+	for _, r := range m.AttachmentsRemote {
+		if r != nil && inline[remoteBaseName(r.RemoteLink)] {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	m.AttachmentsRemote = kept
+	return m
+}
+
+// remoteAttachmentNames returns the set of base names of the remote links
+func remoteAttachmentNames(remotes []*MailAttachmentRemote) map[string]bool {
+	names := make(map[string]bool, len(remotes))
+	for _, r := range remotes {
+		if r == nil {
+			continue
+		}
+		if name := remoteBaseName(r.RemoteLink); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// remoteBaseName returns the last path segment of a remote link
+func remoteBaseName(link string) string {
+	p := link
+	if u, err := url.Parse(link); err == nil {
+		p = u.Path
+	}
+	name := path.Base(p)
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// SetSubject sets the subject line
+func (m *MailSendRequest) SetSubject(subject string) *MailSendRequest {
+	m.Subject = subject
+	return m
+}
+
+// SetHTMLContent sets the HTML body. Leave it empty with a TransactionalID
+// set to use the template body.
+func (m *MailSendRequest) SetHTMLContent(html string) *MailSendRequest {
+	m.HTMLContent = html
+	return m
+}
+
+// SetTextContent sets the plain text body. Leave it empty with a
+// TransactionalID set to use the template body.
+func (m *MailSendRequest) SetTextContent(text string) *MailSendRequest {
+	m.TextContent = text
+	return m
+}
+
 // SetReplyTo sets the Reply-To email address
 func (m *MailSendRequest) SetReplyTo(replyTo string) *MailSendRequest {
 	m.ReplyTo = replyTo
 	return m
 }
 
+// SetReplyToChecked sets the Reply-To email address after checking it with
+// ParseEmail. An invalid address is rejected and leaves ReplyTo unchanged.
+func (m *MailSendRequest) SetReplyToChecked(replyTo string) (*MailSendRequest, error) {
+	if _, err := ParseEmail(replyTo); err != nil {
+		return m, err
+	}
+	m.ReplyTo = replyTo
+	return m, nil
+}
+
+// SetReplyToForList sets the Reply-To used for recipients on the given list.
+// A request carries a single Reply-To, so use SplitByReplyToList before sending.
+func (m *MailSendRequest) SetReplyToForList(list, addr string) (*MailSendRequest, error) {
+	if list == "" {
+		return m, errors.New("list should not be empty")
+	}
+	if _, err := ParseEmail(addr); err != nil {
+		return m, fmt.Errorf("reply-to for list %q: %w", list, err)
+	}
+	if m.ReplyToByList == nil {
+		m.ReplyToByList = make(map[string]string)
+	}
+	m.ReplyToByList[list] = addr
+	return m, nil
+}
+
+// SplitByReplyToList fans the request out into one request per Reply-To.
+// Each recipient gets the Reply-To of the first of its Lists that has one
+// set with SetReplyToForList, or the request's own ReplyTo otherwise.
+// Requests are returned in order of their first recipient; they share
+// attachments and custom parameters with m. Cc and Bcc recipients are kept
+// on the first request only, so they receive a single copy. If m has an
+// idempotency key, each request gets its own key derived from it. A request
+// without To recipients is returned alone, unchanged.
+func (m *MailSendRequest) SplitByReplyToList() []*MailSendRequest {
+	if len(m.To) == 0 {
+		return []*MailSendRequest{m}
+	}
+	var order []string
+	groups := make(map[string][]*MailRecipient)
+	for _, r := range m.To {
+		replyTo := m.ReplyTo
+		if r != nil {
+			for _, list := range r.Lists {
+				if addr, ok := m.ReplyToByList[list]; ok {
+					replyTo = addr
+					break
+				}
+			}
+		}
+		if _, seen := groups[replyTo]; !seen {
+			order = append(order, replyTo)
+		}
+		groups[replyTo] = append(groups[replyTo], r)
+	}
+
+	requests := make([]*MailSendRequest, 0, len(order))
+	for i, replyTo := range order {
+		req := *m
+		req.To = groups[replyTo]
+		req.ReplyTo = replyTo
+		req.ReplyToByList = nil
+		req.idempotencyKey = m.partIdempotencyKey(i)
+		if i > 0 {
+			req.Cc = make([]*MailRecipient, 0)
+			req.Bcc = make([]*MailRecipient, 0)
+		}
+		requests = append(requests, &req)
+	}
+	return requests
+}
+
+// ChunkByRecipients splits the request into copies of at most max To
+// recipients each, in order, for the API's per-request recipient limit.
+// Each copy is a Clone of m, so all other fields are preserved, including
+// Cc and Bcc, which therefore receive one message per copy, and changing a
+// copy never affects m or the other copies. Each copy gets its own
+// idempotency key derived from m's. If max <= 0 or the request already
+// fits, m is returned alone.
+func (m *MailSendRequest) ChunkByRecipients(max int) []*MailSendRequest {
+	if max <= 0 || len(m.To) <= max {
+		return []*MailSendRequest{m}
+	}
+	requests := make([]*MailSendRequest, 0, (len(m.To)+max-1)/max)
+	for start := 0; start < len(m.To); start += max {
+		end := start + max
+		if end > len(m.To) {
+			end = len(m.To)
+		}
+		req := m.Clone()
+		req.To = req.To[start:end:end]
+		req.idempotencyKey = m.partIdempotencyKey(len(requests))
+		requests = append(requests, req)
+	}
+	return requests
+}
+
+// SetMaxTotalAttachmentBytes sets the limit Validate applies to the decoded
+// size of all inline attachments. A value <= 0 restores the default.
+func (m *MailSendRequest) SetMaxTotalAttachmentBytes(n int64) *MailSendRequest {
+	m.maxTotalAttachmentBytes = n
+	return m
+}
+
 // SetCustomParameter adds a custom parameter key/value
 func (m *MailSendRequest) SetCustomParameter(key string, value interface{}) *MailSendRequest {
 	m.CustomParameter[key] = value
@@ -121,6 +443,114 @@ func (m *MailSendRequest) SetScheduledAt(scheduledAt string) *MailSendRequest {
 	return m
 }
 
+// SetTransactionalID sets the transactional template to send. Validate
+// checks that it is made of letters, digits, dashes, underscores and dots.
+func (m *MailSendRequest) SetTransactionalID(id string) *MailSendRequest {
+	m.TransactionalID = id
+	return m
+}
+
+// validateTransactionalID checks the charset of a transactional ID
+func validateTransactionalID(id string) error {
+	if strings.TrimSpace(id) == "" {
+		return errors.New("Invalid transactional ID. It should not be empty.")
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return fmt.Errorf("Invalid transactional ID. Character %q is not allowed.", c)
+		}
+	}
+	return nil
+}
+
+// SetConversationID sets the application-level conversation the message belongs to.
+// The ID must be at most 128 characters of letters, digits, '-', '_', '.' or ':'.
+func (m *MailSendRequest) SetConversationID(id string) (*MailSendRequest, error) {
+	if err := validateConversationID(id); err != nil {
+		return m, err
+	}
+	m.ConversationID = id
+	return m, nil
+}
+
+// validateConversationID checks the length and charset of a conversation ID
+func validateConversationID(id string) error {
+	if id == "" {
+		return fmt.Errorf("Invalid conversation ID. It should not be empty.")
+	}
+	if len(id) > maxConversationIDLength {
+		return fmt.Errorf("Invalid conversation ID. Length should not exceed %d characters.", maxConversationIDLength)
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return fmt.Errorf("Invalid conversation ID. Character %q is not allowed.", c)
+		}
+	}
+	return nil
+}
+
+// SetContentLanguage sets the Content-Language of the message as a BCP 47 tag
+func (m *MailSendRequest) SetContentLanguage(lang string) (*MailSendRequest, error) {
+	if err := validateLanguageTag(lang); err != nil {
+		return m, err
+	}
+	m.ContentLanguage = lang
+	return m, nil
+}
+
+// validateLanguageTag checks that tag is a well-formed BCP 47 language tag.
+// It checks the syntax only, not that the subtags are registered.
+func validateLanguageTag(tag string) error {
+	subtags := strings.Split(tag, "-")
+	for i, sub := range subtags {
+		if len(sub) == 0 || len(sub) > 8 {
+			return fmt.Errorf("Invalid language tag %q.", tag)
+		}
+		for _, c := range sub {
+			isLetter := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+			if !isLetter && (i == 0 || c < '0' || c > '9') {
+				return fmt.Errorf("Invalid language tag %q.", tag)
+			}
+		}
+	}
+	primary := strings.ToLower(subtags[0])
+	if primary == "x" || primary == "i" {
+		if len(subtags) < 2 {
+			return fmt.Errorf("Invalid language tag %q.", tag)
+		}
+		return nil
+	}
+	if len(primary) < 2 {
+		return fmt.Errorf("Invalid language tag %q.", tag)
+	}
+	return nil
+}
+
+// SetScheduledAtTime sets the scheduled sending time, formatted as RFC3339 in UTC.
+// The zero time clears the schedule.
+func (m *MailSendRequest) SetScheduledAtTime(t time.Time) *MailSendRequest {
+	if t.IsZero() {
+		m.ScheduledAt = ""
+		return m
+	}
+	m.ScheduledAt = t.UTC().Format(time.RFC3339)
+	return m
+}
+
+// ScheduledAtTime parses the scheduled sending time. It returns the zero
+// time when no schedule is set.
+func (m *MailSendRequest) ScheduledAtTime() (time.Time, error) {
+	if m.ScheduledAt == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, m.ScheduledAt)
+}
+
 // Simple helpers for flags, feel free to add more as needed
 func (m *MailSendRequest) SetAllowClickTracking(enable bool) *MailSendRequest {
 	m.AllowClickTracking = enable
@@ -147,15 +577,100 @@ func (m *MailSendRequest) SetEnableViewInBrowser(enable bool) *MailSendRequest {
 	return m
 }
 
-// GetRequestBody marshals the request to JSON
+// SetTracking sets open and click tracking in one call
+func (m *MailSendRequest) SetTracking(open, click bool) *MailSendRequest {
+	m.AllowOpenTracking = open
+	m.AllowClickTracking = click
+	return m
+}
+
+// SetBypass sets the bounce control and unsubscribe list bypass flags in one call
+func (m *MailSendRequest) SetBypass(bounce, unsubscribe bool) *MailSendRequest {
+	m.BypassBounceControl = bounce
+	m.BypassUnsubscribeList = unsubscribe
+	return m
+}
+
+// MarshalRequest marshals the request to JSON
+func MarshalRequest(m *MailSendRequest) ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// String summarizes the request for logs and debugging: recipient counts,
+// subject and the number and decoded size of attachments. Attachment data,
+// addresses and attributes are left out.
+func (m *MailSendRequest) String() string {
+	var size int
+	for _, a := range m.Attachments {
+		size += attachmentSize(a)
+	}
+	return fmt.Sprintf("MailSendRequest{to: %d, cc: %d, bcc: %d, subject: %q, attachments: %d (%d bytes), remote attachments: %d}",
+		len(m.To), len(m.Cc), len(m.Bcc), m.Subject, len(m.Attachments), size, len(m.AttachmentsRemote))
+}
+
+// EncodeTo writes the request as JSON to w, followed by a newline, without
+// buffering the whole body first. Use it to stream large requests into an
+// HTTP request body or a file.
+func (m *MailSendRequest) EncodeTo(w io.Writer) error {
+	return json.NewEncoder(w).Encode(m)
+}
+
+// EstimatedSize returns the size in bytes of the request marshaled to JSON,
+// attachments included, to compare with MaxPayloadBytes before sending.
+func (m *MailSendRequest) EstimatedSize() (int, error) {
+	b, err := MarshalRequest(m)
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// GetRequestBody marshals the request to JSON. A marshaling error is only
+// logged and nil is returned; use MarshalRequest to handle it.
 func GetRequestBody(m *MailSendRequest) []byte {
-	b, err := json.Marshal(m)
+	b, err := MarshalRequest(m)
 	if err != nil {
 		log.Println(err)
 	}
 	return b
 }
 
+// ParseMailSendRequest unmarshals a request marshaled with MarshalRequest or
+// GetRequestBody. Slices and maps left nil by the JSON are initialized as
+// NewMailSendRequest and NewMailRecipient do, so builder methods keep working.
+func ParseMailSendRequest(b []byte) (*MailSendRequest, error) {
+	var m MailSendRequest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	if m.To == nil {
+		m.To = make([]*MailRecipient, 0)
+	}
+	if m.Cc == nil {
+		m.Cc = make([]*MailRecipient, 0)
+	}
+	if m.Bcc == nil {
+		m.Bcc = make([]*MailRecipient, 0)
+	}
+	if m.Attachments == nil {
+		m.Attachments = make([]*MailAttachment, 0)
+	}
+	if m.AttachmentsRemote == nil {
+		m.AttachmentsRemote = make([]*MailAttachmentRemote, 0)
+	}
+	if m.CustomParameter == nil {
+		m.CustomParameter = make(map[string]interface{})
+	}
+	for _, recipients := range [][]*MailRecipient{m.To, m.Cc, m.Bcc} {
+		for _, r := range recipients {
+			if r != nil {
+				initRecipient(r)
+			}
+		}
+	}
+	return &m, nil
+}
+
 // NewMailRecipient returns an empty recipient struct
 func NewMailRecipient(name, email string) *MailRecipient {
 	return &MailRecipient{
@@ -176,6 +691,24 @@ func NewMailAttachment(filename, contentType, data string) *MailAttachment {
 	}
 }
 
+// NewVCardAttachment returns a text/vcard attachment with the base64-encoded vCard
+func NewVCardAttachment(filename string, vcard []byte) *MailAttachment {
+	return NewMailAttachment(filename, "text/vcard", base64.StdEncoding.EncodeToString(vcard))
+}
+
+// ValidateVCard checks that vcard is wrapped in BEGIN:VCARD and END:VCARD lines
+func ValidateVCard(vcard []byte) error {
+	lines := strings.FieldsFunc(string(vcard), func(r rune) bool { return r == '\r' || r == '\n' })
+	if len(lines) < 2 {
+		return errors.New("Invalid vCard. It should start with BEGIN:VCARD and end with END:VCARD.")
+	}
+	first, last := strings.TrimSpace(lines[0]), strings.TrimSpace(lines[len(lines)-1])
+	if !strings.EqualFold(first, "BEGIN:VCARD") || !strings.EqualFold(last, "END:VCARD") {
+		return errors.New("Invalid vCard. It should start with BEGIN:VCARD and end with END:VCARD.")
+	}
+	return nil
+}
+
 // NewMailAttachmentRemote returns an empty remote attachment
 func NewMailAttachmentRemote(remoteLink string) *MailAttachmentRemote {
 	return &MailAttachmentRemote{
@@ -183,28 +716,157 @@ func NewMailAttachmentRemote(remoteLink string) *MailAttachmentRemote {
 	}
 }
 
+// Codes of the errors returned by ParseEmail
+const (
+	ParseErrInvalidSyntax = "invalid_syntax"
+	ParseErrInvalidDomain = "invalid_domain"
+	ParseErrTooLong       = "too_long"
+	ParseErrTooLongDomain = "too_long_domain"
+	ParseErrTooLongLocal  = "too_long_local"
+)
+
+// ParseError is returned by ParseEmail. Code is one of the ParseErr
+// constants and is stable, so it can be used to pick a localized message.
+type ParseError struct {
+	Code string
+	Err  error
+}
+
+// Error returns the human-readable message
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// idnaProfile converts internationalized domains to their ASCII form
+var idnaProfile = idna.New(idna.ValidateLabels(true), idna.VerifyDNSLength(true), idna.BidiRule())
+
 // ParseEmail parses a string that contains an rfc822 formatted email address
-// and returns an instance of *Email.
+// and returns an instance of *Email. Errors are of type *ParseError.
+// Internationalized domains are converted to punycode before the length
+// checks, and the returned recipient holds the ASCII form of the address
+// with its domain lowercased; the local part keeps its case. Surrounding
+// whitespace is ignored.
 func ParseEmail(emailInfo string) (*MailRecipient, error) {
+	emailInfo = strings.TrimSpace(emailInfo)
+	if emailInfo == "" {
+		return nil, &ParseError{ParseErrInvalidSyntax, errors.New("Invalid email. It should not be empty.")}
+	}
 	e, err := mail.ParseAddress(emailInfo)
 	if err != nil {
-		return nil, err
+		return nil, &ParseError{ParseErrInvalidSyntax, err}
 	}
+	return recipientFromAddress(e)
+}
 
-	if len(e.Address) > maxEmailLength {
-		return nil, fmt.Errorf("Invalid email length. Total length should not exceed %d characters.", maxEmailLength)
+// NormalizeEmail returns the address in s as ParseEmail stores it: without
+// the display name, with an ASCII, lowercase domain and the local part as is.
+func NormalizeEmail(s string) (string, error) {
+	r, err := ParseEmail(s)
+	if err != nil {
+		return "", err
 	}
+	return r.Email, nil
+}
 
-	parts := strings.Split(e.Address, "@")
-	local, domain := parts[0], parts[1]
+// ParseEmailList parses a comma-separated list of rfc822 formatted email
+// addresses, such as the value of a To header, applying the checks of
+// ParseEmail to each. An error names the offending entry.
+func ParseEmailList(s string) ([]*MailRecipient, error) {
+	addresses, err := mail.ParseAddressList(s)
+	if err != nil {
+		// ParseAddressList does not say which entry failed, so find it.
+		for _, entry := range splitAddressList(s) {
+			if _, entryErr := mail.ParseAddress(entry); entryErr != nil {
+				return nil, fmt.Errorf("address %q: %w", entry, &ParseError{ParseErrInvalidSyntax, entryErr})
+			}
+		}
+		return nil, &ParseError{ParseErrInvalidSyntax, err}
+	}
+
+	recipients := make([]*MailRecipient, 0, len(addresses))
+	for _, e := range addresses {
+		r, err := recipientFromAddress(e)
+		if err != nil {
+			return nil, fmt.Errorf("address %q: %w", e.Address, err)
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, nil
+}
+
+// splitAddressList splits s on the commas that are outside of quoted
+// strings, comments and angle brackets, trimming each entry.
+func splitAddressList(s string) []string {
+	var entries []string
+	var quoted, escaped bool
+	var comment, angle int
+	start := 0
+	for i, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"' && comment == 0:
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			comment++
+		case c == ')' && comment > 0:
+			comment--
+		case c == '<' && comment == 0:
+			angle++
+		case c == '>' && comment == 0 && angle > 0:
+			angle--
+		case c == ',' && comment == 0 && angle == 0:
+			entries = append(entries, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(entries, strings.TrimSpace(s[start:]))
+}
+
+// recipientFromAddress applies the length checks of ParseEmail to a parsed address
+func recipientFromAddress(e *mail.Address) (*MailRecipient, error) {
+	var err error
+	at := strings.LastIndex(e.Address, "@")
+	local, domain := e.Address[:at], e.Address[at+1:]
+
+	if !isASCII(domain) {
+		domain, err = idnaProfile.ToASCII(domain)
+		if err != nil {
+			return nil, &ParseError{ParseErrInvalidDomain, fmt.Errorf("Invalid email domain. %v", err)}
+		}
+	}
+	domain = strings.ToLower(domain)
+	address := local + "@" + domain
+
+	if len(address) > maxEmailLength {
+		return nil, &ParseError{ParseErrTooLong, fmt.Errorf("Invalid email length. Total length should not exceed %d characters.", maxEmailLength)}
+	}
 
 	if len(domain) > maxEmailDomainLength {
-		return nil, fmt.Errorf("Invalid email length. Domain length should not exceed %d characters.", maxEmailDomainLength)
+		return nil, &ParseError{ParseErrTooLongDomain, fmt.Errorf("Invalid email length. Domain length should not exceed %d characters.", maxEmailDomainLength)}
 	}
 
 	if len(local) > maxEmailLocalLength {
-		return nil, fmt.Errorf("Invalid email length. Local part length should not exceed %d characters.", maxEmailLocalLength)
+		return nil, &ParseError{ParseErrTooLongLocal, fmt.Errorf("Invalid email length. Local part length should not exceed %d characters.", maxEmailLocalLength)}
 	}
 
-	return NewMailRecipient(e.Name, e.Address), nil
+	return NewMailRecipient(e.Name, address), nil
+}
+
+// isASCII reports whether s only contains ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
 }
@@ -1,7 +1,14 @@
 package mail
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -13,3 +20,537 @@ func TestV3NewMail(t *testing.T) {
 	assert.NotNil(t, m, "NewMailSendRequest() shouldn't return nil")
 	assert.NotNil(t, m.Attachments, "Attachments shouldn't be nil")
 }
+
+func TestSetTrackingAndBypass(t *testing.T) {
+	m := NewMailSendRequest().SetTracking(true, false).SetBypass(false, true)
+
+	assert.True(t, m.AllowOpenTracking, "open tracking should be enabled")
+	assert.False(t, m.AllowClickTracking, "click tracking should be disabled")
+	assert.False(t, m.BypassBounceControl, "bounce control bypass should be disabled")
+	assert.True(t, m.BypassUnsubscribeList, "unsubscribe list bypass should be enabled")
+
+	m.SetTracking(false, true).SetBypass(true, false)
+	assert.False(t, m.AllowOpenTracking)
+	assert.True(t, m.AllowClickTracking)
+	assert.True(t, m.BypassBounceControl)
+	assert.False(t, m.BypassUnsubscribeList)
+}
+
+func TestDuplicateAttachments(t *testing.T) {
+	m := NewMailSendRequest().
+		AddAttachment(
+			NewMailAttachment("report.pdf", "application/pdf", "ZGF0YQ=="),
+			NewMailAttachment("logo.png", "image/png", "ZGF0YQ=="),
+		).
+		AddRemoteAttachment(
+			NewMailAttachmentRemote("https://cdn.example.com/files/report.pdf?v=2"),
+			NewMailAttachmentRemote("https://cdn.example.com/files/terms.pdf"),
+		)
+
+	assert.Equal(t, []string{"report.pdf"}, m.DuplicateAttachments())
+
+	inline := NewMailSendRequest().AddAttachment(m.Attachments...).AddRemoteAttachment(m.AttachmentsRemote...)
+	shared := inline.Attachments
+	inline.DropInlineDuplicates()
+	assert.Equal(t, "report.pdf", shared[0].Filename, "dropping should not overwrite the previous slice")
+	assert.Len(t, inline.Attachments, 1)
+	assert.Equal(t, "logo.png", inline.Attachments[0].Filename)
+	assert.Len(t, inline.AttachmentsRemote, 2)
+	assert.Empty(t, inline.DuplicateAttachments())
+
+	remote := NewMailSendRequest().AddAttachment(m.Attachments...).AddRemoteAttachment(m.AttachmentsRemote...)
+	remote.DropRemoteDuplicates()
+	assert.Len(t, remote.Attachments, 2)
+	assert.Len(t, remote.AttachmentsRemote, 1)
+	assert.Equal(t, "https://cdn.example.com/files/terms.pdf", remote.AttachmentsRemote[0].RemoteLink)
+	assert.Empty(t, remote.DuplicateAttachments())
+}
+
+func TestSetConversationID(t *testing.T) {
+	m, err := NewMailSendRequest().SetConversationID("order-1234:thread_1.a")
+	assert.Nil(t, err)
+	assert.Equal(t, "order-1234:thread_1.a", m.ConversationID)
+	assert.Contains(t, string(GetRequestBody(m)), `"conversation_id":"order-1234:thread_1.a"`)
+
+	for _, id := range []string{"", "has space", "emoji☃", strings.Repeat("a", 129)} {
+		m, err := NewMailSendRequest().SetConversationID(id)
+		assert.NotNil(t, err, "conversation ID %q should be rejected", id)
+		assert.Empty(t, m.ConversationID)
+	}
+	assert.NotContains(t, string(GetRequestBody(NewMailSendRequest())), "conversation_id")
+}
+
+func TestSetTransactionalID(t *testing.T) {
+	m := NewMailSendRequest().
+		SetTransactionalID("welcome-2024").
+		AddRecipient(NewMailRecipient("Jane", "jane@example.com")).
+		SetSubject("Welcome")
+	assert.Equal(t, "welcome-2024", m.TransactionalID)
+	assert.Nil(t, m.Validate())
+
+	for _, id := range []string{"   ", "welcome 2024", "welcome/2024", "café"} {
+		err := m.SetTransactionalID(id).Validate()
+		if assert.NotNil(t, err, "transactional ID %q should be rejected", id) {
+			assert.Contains(t, err.Error(), "Invalid transactional ID")
+		}
+	}
+	for _, id := range []string{"welcome_email", "onboarding.v2", "order-shipped_v1.2"} {
+		assert.Nil(t, m.SetTransactionalID(id).Validate(), "transactional ID %q should be accepted", id)
+	}
+	assert.Nil(t, m.SetTransactionalID("").Validate(), "an unset ID is not checked")
+}
+
+func TestSetReplyToChecked(t *testing.T) {
+	m, err := NewMailSendRequest().SetReplyToChecked("support@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "support@example.com", m.ReplyTo)
+
+	m, err = m.SetReplyToChecked("not an address")
+	assert.NotNil(t, err)
+	assert.Equal(t, "support@example.com", m.ReplyTo, "an invalid address should leave ReplyTo unchanged")
+}
+
+func TestSetContentLanguage(t *testing.T) {
+	for _, lang := range []string{"en", "pt-BR", "zh-Hant-TW", "es-419", "x-klingon"} {
+		m, err := NewMailSendRequest().SetContentLanguage(lang)
+		assert.Nil(t, err, "language tag %q should be accepted", lang)
+		assert.Equal(t, lang, m.ContentLanguage)
+	}
+	for _, lang := range []string{"", "e", "en_US", "en-", "en-toolongsubtag", "1en", "x"} {
+		m, err := NewMailSendRequest().SetContentLanguage(lang)
+		assert.NotNil(t, err, "language tag %q should be rejected", lang)
+		assert.Empty(t, m.ContentLanguage)
+	}
+
+	m, _ := NewMailSendRequest().SetContentLanguage("de")
+	assert.Contains(t, string(GetRequestBody(m)), `"content_language":"de"`)
+}
+
+func TestParseEmail(t *testing.T) {
+	r, err := ParseEmail("Jane Doe <jane@example.com>")
+	assert.Nil(t, err)
+	assert.Equal(t, "Jane Doe", r.Name)
+	assert.Equal(t, "jane@example.com", r.Email)
+}
+
+func TestParseEmailInternationalDomain(t *testing.T) {
+	r, err := ParseEmail("Jürgen Müller <user@münchen.de>")
+	assert.Nil(t, err)
+	assert.Equal(t, "user@xn--mnchen-3ya.de", r.Email, "the domain should be stored as punycode")
+	assert.Equal(t, "Jürgen Müller", r.Name, "the display name should be untouched")
+
+	r, err = ParseEmail("user@example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "user@example.com", r.Email, "ASCII domains should be unchanged")
+
+	_, err = ParseEmail("user@bad\u200dname.de")
+	var parseErr *ParseError
+	if assert.True(t, errors.As(err, &parseErr), "invalid IDNs should be rejected") {
+		assert.Equal(t, ParseErrInvalidDomain, parseErr.Code)
+	}
+}
+
+func TestParseEmailTrimsSpace(t *testing.T) {
+	r, err := ParseEmail("  user@x.com  ")
+	assert.Nil(t, err)
+	assert.Equal(t, "user@x.com", r.Email)
+
+	for _, blank := range []string{"", "   ", "\t\n"} {
+		_, err = ParseEmail(blank)
+		var parseErr *ParseError
+		if assert.True(t, errors.As(err, &parseErr), "%q should fail with a *ParseError", blank) {
+			assert.Equal(t, ParseErrInvalidSyntax, parseErr.Code)
+			assert.Contains(t, err.Error(), "should not be empty")
+		}
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	for input, want := range map[string]string{
+		"jane@Example.COM":                 "jane@example.com",
+		"Jane.Doe@EXAMPLE.com":             "Jane.Doe@example.com",
+		"Jane Doe <JANE@Mail.Example.Org>": "JANE@mail.example.org",
+		"user@Bücher.Example":              "user@xn--bcher-kva.example",
+	} {
+		got, err := NormalizeEmail(input)
+		assert.Nil(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	r, err := ParseEmail("Jane <Jane@Example.com>")
+	assert.Nil(t, err)
+	assert.Equal(t, "Jane@example.com", r.Email, "ParseEmail should store the normalized address")
+
+	_, err = NormalizeEmail("not an address")
+	assert.NotNil(t, err)
+}
+
+func TestParseEmailErrorCodes(t *testing.T) {
+	cases := map[string]string{
+		"not an email":                                           ParseErrInvalidSyntax,
+		strings.Repeat("a", 65) + "@example.com":                 ParseErrTooLongLocal,
+		"a@" + strings.Repeat("b", 252) + ".com":                 ParseErrTooLongDomain,
+		strings.Repeat("a", 64) + "@" + strings.Repeat("b", 256): ParseErrTooLong,
+	}
+	for input, code := range cases {
+		_, err := ParseEmail(input)
+		var parseErr *ParseError
+		if assert.True(t, errors.As(err, &parseErr), "error for %q should be a *ParseError", input) {
+			assert.Equal(t, code, parseErr.Code)
+			assert.NotEmpty(t, parseErr.Error(), "the human-readable message should be kept")
+			assert.Equal(t, parseErr.Err, errors.Unwrap(err))
+		}
+	}
+}
+
+func TestSplitByReplyToList(t *testing.T) {
+	sales := NewMailRecipient("Sam", "sam@example.com")
+	sales.Lists = append(sales.Lists, "sales")
+	support := NewMailRecipient("Sue", "sue@example.com")
+	support.Lists = append(support.Lists, "other", "support")
+	other := NewMailRecipient("Oli", "oli@example.com")
+	sales2 := NewMailRecipient("Sid", "sid@example.com")
+	sales2.Lists = append(sales2.Lists, "sales", "support")
+
+	m := NewMailSendRequest().AddRecipient(sales, support, other, sales2).SetReplyTo("hello@example.com")
+	_, err := m.SetReplyToForList("sales", "sales@example.com")
+	assert.Nil(t, err)
+	_, err = m.SetReplyToForList("support", "support@example.com")
+	assert.Nil(t, err)
+	assert.NotContains(t, string(GetRequestBody(m)), "sales@example.com", "per-list reply-to should not be sent")
+
+	m.AddCc(NewMailRecipient("Manager", "manager@example.com"))
+	requests := m.SplitByReplyToList()
+	assert.Len(t, requests, 3)
+	assert.Len(t, requests[0].Cc, 1, "Cc should be kept on the first request")
+	assert.Empty(t, requests[1].Cc, "Cc should receive a single copy")
+	assert.Equal(t, "sales@example.com", requests[0].ReplyTo)
+	assert.Equal(t, []*MailRecipient{sales, sales2}, requests[0].To)
+	assert.Equal(t, "support@example.com", requests[1].ReplyTo)
+	assert.Equal(t, []*MailRecipient{support}, requests[1].To)
+	assert.Equal(t, "hello@example.com", requests[2].ReplyTo)
+	assert.Equal(t, []*MailRecipient{other}, requests[2].To)
+	assert.Len(t, m.To, 4, "the original request should be unchanged")
+	assert.Equal(t, "hello@example.com", m.ReplyTo)
+
+	m.SetIdempotencyKey("order-42")
+	requests = m.SplitByReplyToList()
+	assert.Equal(t, "order-42-1", requests[0].IdempotencyKey())
+	assert.Equal(t, "order-42-3", requests[2].IdempotencyKey())
+}
+
+func TestSplitAndChunkWithoutRecipients(t *testing.T) {
+	m := NewMailSendRequest().AddCc(NewMailRecipient("Manager", "manager@example.com")).SetIdempotencyKey("order-42")
+	assert.Equal(t, []*MailSendRequest{m}, m.SplitByReplyToList(), "a request without To should be returned unchanged")
+	assert.Equal(t, []*MailSendRequest{m}, m.ChunkByRecipients(2), "a request without To should be returned unchanged")
+	assert.Equal(t, "order-42", m.IdempotencyKey())
+}
+
+func TestChunkByRecipients(t *testing.T) {
+	m := NewMailSendRequest().SetSubject("Hello").AddCc(NewMailRecipient("Boss", "boss@example.com"))
+	m.TransactionalID = "welcome"
+	for i := 0; i < 6; i++ {
+		m.AddRecipient(NewMailRecipient("", fmt.Sprintf("user%d@example.com", i)))
+	}
+
+	chunks := m.ChunkByRecipients(3)
+	if assert.Len(t, chunks, 2) {
+		assert.Equal(t, m.To[:3], chunks[0].To)
+		assert.Equal(t, m.To[3:], chunks[1].To)
+		assert.Len(t, chunks[0].Cc, 1)
+		assert.Len(t, chunks[1].Cc, 1, "every chunk should keep Cc")
+		for _, chunk := range chunks {
+			assert.Equal(t, "Hello", chunk.Subject)
+			assert.Equal(t, "welcome", chunk.TransactionalID)
+		}
+	}
+
+	chunks = m.ChunkByRecipients(4)
+	if assert.Len(t, chunks, 2) {
+		assert.Len(t, chunks[0].To, 4)
+		assert.Len(t, chunks[1].To, 2)
+	}
+	chunks[0].AddRecipient(NewMailRecipient("", "extra@example.com"))
+	assert.Len(t, m.To, 6, "chunks should not share the To slice with the original")
+	assert.Len(t, chunks[1].To, 2, "chunks should not share the To slice with each other")
+	chunks[0].SetCustomParameter("plan", "pro")
+	chunks[0].Cc[0].Name = "Changed"
+	assert.NotContains(t, chunks[1].CustomParameter, "plan", "chunks should not share custom parameters")
+	assert.Equal(t, "Boss", chunks[1].Cc[0].Name, "chunks should not share recipients")
+	assert.Equal(t, "Boss", m.Cc[0].Name)
+
+	assert.Equal(t, []*MailSendRequest{m}, m.ChunkByRecipients(0))
+	assert.Equal(t, []*MailSendRequest{m}, m.ChunkByRecipients(-1))
+	assert.Equal(t, []*MailSendRequest{m}, m.ChunkByRecipients(6))
+}
+
+func TestChunkByRecipientsIdempotencyKey(t *testing.T) {
+	m := NewMailSendRequest().SetIdempotencyKey("order-42")
+	for i := 0; i < 3; i++ {
+		m.AddRecipient(NewMailRecipient("", fmt.Sprintf("user%d@example.com", i)))
+	}
+	chunks := m.ChunkByRecipients(2)
+	if assert.Len(t, chunks, 2) {
+		assert.Equal(t, "order-42-1", chunks[0].IdempotencyKey())
+		assert.Equal(t, "order-42-2", chunks[1].IdempotencyKey())
+	}
+	assert.Equal(t, "order-42", m.IdempotencyKey(), "the original key should be unchanged")
+
+	m.SetIdempotencyKey("")
+	for _, chunk := range m.ChunkByRecipients(2) {
+		assert.Empty(t, chunk.IdempotencyKey())
+	}
+	assert.Empty(t, m.SetIdempotencyKey("order-42").Clone().IdempotencyKey(), "a clone should not share the key")
+}
+
+func TestSetReplyToForListInvalid(t *testing.T) {
+	m := NewMailSendRequest()
+	_, err := m.SetReplyToForList("sales", "not-an-email")
+	assert.NotNil(t, err)
+	_, err = m.SetReplyToForList("", "sales@example.com")
+	assert.NotNil(t, err)
+	assert.Empty(t, m.ReplyToByList)
+}
+
+func TestNewVCardAttachment(t *testing.T) {
+	vcard := []byte("BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Cocoonmail Support\r\nEMAIL:support@cocoonmail.com\r\nEND:VCARD\r\n")
+	assert.Nil(t, ValidateVCard(vcard))
+
+	a := NewVCardAttachment("support.vcf", vcard)
+	assert.Equal(t, "support.vcf", a.Filename)
+	assert.Equal(t, "text/vcard", a.ContentType)
+	decoded, err := base64.StdEncoding.DecodeString(a.Data)
+	assert.Nil(t, err)
+	assert.Equal(t, vcard, decoded)
+}
+
+func TestValidateVCard(t *testing.T) {
+	assert.Nil(t, ValidateVCard([]byte("begin:vcard\nFN:Jane\nend:vcard")))
+	assert.NotNil(t, ValidateVCard(nil))
+	assert.NotNil(t, ValidateVCard([]byte("BEGIN:VCARD\nFN:Jane\n")))
+	assert.NotNil(t, ValidateVCard([]byte("FN:Jane\nEND:VCARD")))
+}
+
+func TestSetSubjectAndContent(t *testing.T) {
+	m := NewMailSendRequest().
+		SetSubject("Welcome").
+		SetHTMLContent("<p>Hello</p>").
+		SetTextContent("Hello")
+	var fields map[string]interface{}
+	assert.Nil(t, json.Unmarshal(GetRequestBody(m), &fields))
+	assert.Equal(t, "Welcome", fields["subject"])
+	assert.Equal(t, "<p>Hello</p>", fields["html"])
+	assert.Equal(t, "Hello", fields["text"])
+
+	m = NewMailSendRequest()
+	m.TransactionalID = "welcome-v2"
+	m.SetHTMLContent("").SetTextContent("")
+	body := string(GetRequestBody(m))
+	assert.Contains(t, body, `"transactional_id":"welcome-v2"`)
+	assert.NotContains(t, body, `"subject"`)
+	assert.NotContains(t, body, `"html"`, "empty HTML should be omitted so the template is used")
+	assert.NotContains(t, body, `"text"`, "empty text should be omitted so the template is used")
+}
+
+func TestCcAndBcc(t *testing.T) {
+	m := NewMailSendRequest()
+	assert.NotNil(t, m.Cc, "Cc shouldn't be nil")
+	assert.NotNil(t, m.Bcc, "Bcc shouldn't be nil")
+	body := string(GetRequestBody(m))
+	assert.NotContains(t, body, `"cc"`)
+	assert.NotContains(t, body, `"bcc"`)
+
+	m.AddRecipient(NewMailRecipient("Jane", "jane@example.com")).
+		AddCc(NewMailRecipient("John", "john@example.com"), NewMailRecipient("Joan", "joan@example.com")).
+		AddBcc(NewMailRecipient("Audit", "audit@example.com"))
+
+	var payload struct {
+		To  []MailRecipient `json:"to"`
+		Cc  []MailRecipient `json:"cc"`
+		Bcc []MailRecipient `json:"bcc"`
+	}
+	assert.Nil(t, json.Unmarshal(GetRequestBody(m), &payload))
+	assert.Len(t, payload.To, 1)
+	assert.Equal(t, "jane@example.com", payload.To[0].Email)
+	assert.Len(t, payload.Cc, 2)
+	assert.Equal(t, "joan@example.com", payload.Cc[1].Email)
+	assert.Len(t, payload.Bcc, 1)
+	assert.Equal(t, "audit@example.com", payload.Bcc[0].Email)
+}
+
+func TestMarshalRequest(t *testing.T) {
+	m := NewMailSendRequest().SetSubject("Hello")
+	b, err := MarshalRequest(m)
+	assert.Nil(t, err)
+	assert.Equal(t, GetRequestBody(m), b)
+
+	m.SetCustomParameter("callback", make(chan int))
+	b, err = MarshalRequest(m)
+	assert.NotNil(t, err, "a channel value should fail to marshal")
+	assert.Nil(t, b)
+	assert.Nil(t, GetRequestBody(m), "GetRequestBody should ignore the error and return nil")
+}
+
+func TestParseEmailList(t *testing.T) {
+	recipients, err := ParseEmailList(`Alice <a@x.com>, "Bob, Jr." <b@y.com>, c@z.com`)
+	assert.Nil(t, err)
+	assert.Len(t, recipients, 3)
+	assert.Equal(t, "Alice", recipients[0].Name)
+	assert.Equal(t, "a@x.com", recipients[0].Email)
+	assert.Equal(t, "Bob, Jr.", recipients[1].Name)
+	assert.Equal(t, "b@y.com", recipients[1].Email)
+	assert.Equal(t, "c@z.com", recipients[2].Email)
+}
+
+func TestParseEmailListMalformedEntry(t *testing.T) {
+	recipients, err := ParseEmailList(`Alice <a@x.com>, Broken <not-an-address>, c@z.com`)
+	assert.Nil(t, recipients)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), `"Broken <not-an-address>"`, "the error should name the offending entry")
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, ParseErrInvalidSyntax, parseErr.Code)
+}
+
+func TestParseEmailListTooLongEntry(t *testing.T) {
+	long := strings.Repeat("a", 65) + "@x.com"
+	_, err := ParseEmailList("a@x.com, " + long)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), long)
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, ParseErrTooLongLocal, parseErr.Code)
+}
+
+func TestSetScheduledAtTime(t *testing.T) {
+	local := time.FixedZone("UTC+2", 2*60*60)
+	at := time.Date(2030, 6, 1, 10, 30, 0, 0, local)
+
+	m := NewMailSendRequest().SetScheduledAtTime(at)
+	assert.Equal(t, "2030-06-01T08:30:00Z", m.ScheduledAt, "the time should be stored as RFC3339 in UTC")
+
+	parsed, err := m.ScheduledAtTime()
+	assert.Nil(t, err)
+	assert.True(t, at.Equal(parsed), "the schedule should round-trip")
+
+	m.SetScheduledAtTime(time.Time{})
+	assert.Empty(t, m.ScheduledAt, "the zero time should clear the schedule")
+	parsed, err = m.ScheduledAtTime()
+	assert.Nil(t, err)
+	assert.True(t, parsed.IsZero())
+
+	m.SetScheduledAt("next tuesday")
+	_, err = m.ScheduledAtTime()
+	assert.NotNil(t, err)
+}
+
+func TestDedupeRecipients(t *testing.T) {
+	jane := NewMailRecipient("Jane", "jane@example.com")
+	jane.FirstName = "Jane"
+	m := NewMailSendRequest().AddRecipient(
+		jane,
+		NewMailRecipient("John", "john@example.com"),
+		NewMailRecipient("JANE", "Jane@Example.COM"),
+		NewMailRecipient("Janet", "jane@example.com"),
+		NewMailRecipient("Joan", "joan@example.com"),
+	)
+
+	m.DedupeRecipients()
+	assert.Len(t, m.To, 3)
+	assert.Equal(t, "jane@example.com", m.To[0].Email)
+	assert.Equal(t, "Jane", m.To[0].Name, "the first occurrence should be kept")
+	assert.Equal(t, "Jane", m.To[0].FirstName, "the first occurrence's attributes should be kept")
+	assert.Equal(t, "john@example.com", m.To[1].Email)
+	assert.Equal(t, "joan@example.com", m.To[2].Email)
+}
+
+func TestAddRecipientEmails(t *testing.T) {
+	m, err := NewMailSendRequest().AddRecipientEmails("jane@example.com", "John Doe <john@example.com>")
+	assert.Nil(t, err)
+	assert.Len(t, m.To, 2)
+	assert.Equal(t, "jane@example.com", m.To[0].Email)
+	assert.Equal(t, "John Doe", m.To[1].Name)
+	assert.Equal(t, "john@example.com", m.To[1].Email)
+
+	m, err = NewMailSendRequest().AddRecipientEmails("jane@example.com", "bogus", "john@example.com")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), `"bogus"`, "the error should name the invalid address")
+	assert.Empty(t, m.To, "no recipients should be appended on error")
+}
+
+func TestAddRecipientChecked(t *testing.T) {
+	m, err := NewMailSendRequest().AddRecipientChecked(NewMailRecipient("Jane", "jane@example.com"))
+	assert.Nil(t, err)
+	assert.Len(t, m.To, 1)
+
+	m, err = m.AddRecipientChecked(
+		NewMailRecipient("John", "john@example.com"),
+		NewMailRecipient("Bogus", "bogus"),
+		nil,
+	)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `recipient 1 "bogus"`, "the error should name the first invalid recipient")
+	}
+	assert.Len(t, m.To, 1, "no recipients should be appended on error")
+
+	_, err = NewMailSendRequest().AddRecipientChecked(nil)
+	assert.NotNil(t, err)
+}
+
+func TestMailSendRequestString(t *testing.T) {
+	data := base64.StdEncoding.EncodeToString([]byte("secret attachment body"))
+	m := validRequest().
+		SetSubject("Your invoice").
+		AddAttachment(NewMailAttachment("invoice.pdf", "application/pdf", data)).
+		AddRemoteAttachment(NewMailAttachmentRemote("https://cdn.example.com/terms.pdf"))
+	m.To[0].SetAttribute("plan", "pro")
+
+	summary := fmt.Sprintf("%v", m)
+	assert.Equal(t, `MailSendRequest{to: 1, cc: 1, bcc: 0, subject: "Your invoice", attachments: 1 (22 bytes), remote attachments: 1}`, summary)
+	assert.NotContains(t, summary, data)
+	assert.NotContains(t, summary, "jane@example.com")
+	assert.NotContains(t, summary, "pro")
+}
+
+func TestEncodeTo(t *testing.T) {
+	m := validRequest().
+		SetHTMLContent("<p>Hi & welcome</p>").
+		AddAttachment(NewMailAttachment("a.txt", "text/plain", "aGk="))
+	var buf bytes.Buffer
+	assert.Nil(t, m.EncodeTo(&buf))
+	assert.Equal(t, string(GetRequestBody(m))+"\n", buf.String())
+
+	m.SetCustomParameter("bad", make(chan int))
+	assert.NotNil(t, m.EncodeTo(&bytes.Buffer{}))
+}
+
+func TestParseMailSendRequest(t *testing.T) {
+	jane := NewMailRecipient("Jane", "jane@example.com")
+	jane.Attributes["plan"] = "pro"
+	jane.Tags = append(jane.Tags, "vip")
+	m := NewMailSendRequest().
+		AddRecipient(jane, NewMailRecipient("John", "john@example.com")).
+		AddBcc(NewMailRecipient("Audit", "audit@example.com")).
+		AddAttachment(NewMailAttachment("a.txt", "text/plain", "aGVsbG8=")).
+		AddRemoteAttachment(NewMailAttachmentRemote("https://cdn.example.com/b.pdf")).
+		SetCustomParameter("order", "1234").
+		SetSubject("Hello").
+		SetReplyTo("support@example.com").
+		SetTracking(true, true)
+	m.TransactionalID = "welcome-v2"
+
+	parsed, err := ParseMailSendRequest(GetRequestBody(m))
+	assert.Nil(t, err)
+	assert.Equal(t, m, parsed)
+}
+
+func TestParseMailSendRequestInitializesEmptyFields(t *testing.T) {
+	parsed, err := ParseMailSendRequest([]byte(`{"to":[{"email":"jane@example.com"}]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, NewMailSendRequest().AddRecipient(NewMailRecipient("", "jane@example.com")), parsed)
+
+	_, err = ParseMailSendRequest([]byte(`{"to": "jane@example.com"}`))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,45 @@
+package mail
+
+import "time"
+
+// MailOption configures a MailSendRequest built by NewMailSendRequestWith
+type MailOption func(*MailSendRequest)
+
+// NewMailSendRequestWith builds a request in one call, applying opts in
+// order to the result of NewMailSendRequest. It is equivalent to calling
+// the matching setters in the same order.
+func NewMailSendRequestWith(opts ...MailOption) *MailSendRequest {
+	m := NewMailSendRequest()
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// WithSubject sets the subject, as SetSubject does
+func WithSubject(subject string) MailOption {
+	return func(m *MailSendRequest) {
+		m.SetSubject(subject)
+	}
+}
+
+// WithRecipients appends To recipients, as AddRecipient does
+func WithRecipients(recipients ...*MailRecipient) MailOption {
+	return func(m *MailSendRequest) {
+		m.AddRecipient(recipients...)
+	}
+}
+
+// WithReplyTo sets the Reply-To address, as SetReplyTo does
+func WithReplyTo(replyTo string) MailOption {
+	return func(m *MailSendRequest) {
+		m.SetReplyTo(replyTo)
+	}
+}
+
+// WithScheduledAt schedules the send, as SetScheduledAtTime does
+func WithScheduledAt(t time.Time) MailOption {
+	return func(m *MailSendRequest) {
+		m.SetScheduledAtTime(t)
+	}
+}
@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMailSendRequestWith(t *testing.T) {
+	at := time.Date(2030, 1, 2, 15, 0, 0, 0, time.UTC)
+	jane := NewMailRecipient("Jane", "jane@example.com")
+	john := NewMailRecipient("John", "john@example.com")
+
+	built := NewMailSendRequestWith(
+		WithSubject("Welcome"),
+		WithRecipients(jane),
+		WithRecipients(john),
+		WithReplyTo("support@example.com"),
+		WithScheduledAt(at),
+	)
+	chained := NewMailSendRequest().
+		SetSubject("Welcome").
+		AddRecipient(jane, john).
+		SetReplyTo("support@example.com").
+		SetScheduledAtTime(at)
+
+	assert.Equal(t, chained, built)
+	assert.Equal(t, "2030-01-02T15:00:00Z", built.ScheduledAt)
+	assert.Equal(t, NewMailSendRequest(), NewMailSendRequestWith())
+}
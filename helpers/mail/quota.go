@@ -0,0 +1,44 @@
+package mail
+
+import "strings"
+
+// QuotaModel describes how a request is charged against the send quota.
+//
+// A request costs UnitsPerRecipient for every To, Cc and Bcc recipient. When
+// AttachmentBytesPerUnit is positive, each started block of that many
+// attachment bytes (decoded, inline attachments only) adds one more unit
+// per recipient, since every recipient receives its own copy.
+type QuotaModel struct {
+	UnitsPerRecipient      int
+	AttachmentBytesPerUnit int
+}
+
+// DefaultQuotaModel charges one unit per recipient and nothing for attachments.
+var DefaultQuotaModel = QuotaModel{UnitsPerRecipient: 1}
+
+// QuotaUnits returns the send units the request consumes under DefaultQuotaModel
+func (m *MailSendRequest) QuotaUnits() int {
+	return m.QuotaUnitsWith(DefaultQuotaModel)
+}
+
+// QuotaUnitsWith returns the send units the request consumes under the given model
+func (m *MailSendRequest) QuotaUnitsWith(q QuotaModel) int {
+	perRecipient := q.UnitsPerRecipient
+	if q.AttachmentBytesPerUnit > 0 {
+		var size int
+		for _, a := range m.Attachments {
+			size += attachmentSize(a)
+		}
+		perRecipient += (size + q.AttachmentBytesPerUnit - 1) / q.AttachmentBytesPerUnit
+	}
+	return (len(m.To) + len(m.Cc) + len(m.Bcc)) * perRecipient
+}
+
+// attachmentSize returns the decoded size of the base64 data of an attachment
+func attachmentSize(a *MailAttachment) int {
+	if a == nil {
+		return 0
+	}
+	data := strings.TrimRight(a.Data, "=")
+	return len(data) * 3 / 4
+}
@@ -0,0 +1,44 @@
+package mail
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaUnits(t *testing.T) {
+	m := NewMailSendRequest().AddRecipient(
+		NewMailRecipient("Jane", "jane@example.com"),
+		NewMailRecipient("John", "john@example.com"),
+		NewMailRecipient("Joan", "joan@example.com"),
+	)
+	assert.Equal(t, 3, m.QuotaUnits(), "default model should charge one unit per recipient")
+
+	data := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", 1500)))
+	m.AddAttachment(NewMailAttachment("a.txt", "text/plain", data))
+	assert.Equal(t, 3, m.QuotaUnits(), "default model should not charge for attachments")
+
+	model := QuotaModel{UnitsPerRecipient: 1, AttachmentBytesPerUnit: 1000}
+	assert.Equal(t, 9, m.QuotaUnitsWith(model), "1500 bytes should add two units per recipient")
+
+	model.UnitsPerRecipient = 2
+	assert.Equal(t, 12, m.QuotaUnitsWith(model))
+
+	assert.Equal(t, 0, NewMailSendRequest().QuotaUnits())
+
+	cc := NewMailSendRequest().
+		AddRecipient(NewMailRecipient("Jane", "jane@example.com")).
+		AddCc(NewMailRecipient("John", "john@example.com")).
+		AddBcc(NewMailRecipient("Joan", "joan@example.com"))
+	assert.Equal(t, 3, cc.QuotaUnits(), "Cc and Bcc recipients should be charged")
+}
+
+func TestAttachmentSize(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 4, 1000} {
+		data := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", n)))
+		assert.Equal(t, n, attachmentSize(NewMailAttachment("a", "text/plain", data)))
+	}
+	assert.Equal(t, 0, attachmentSize(nil))
+}
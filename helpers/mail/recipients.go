@@ -0,0 +1,239 @@
+package mail
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AnniversaryDateLayout is the layout of MailRecipient.AnniversaryDate
+const AnniversaryDateLayout = "2006-01-02"
+
+// RecipientsFromJSON decodes a JSON array of recipients and validates each
+// email with the same rules as ParseEmail. Numbers in attributes are kept as
+// json.Number so large identifiers don't lose precision. All decoded
+// recipients are returned, along with an error listing every invalid entry
+// by its index in the array.
+func RecipientsFromJSON(r io.Reader) ([]*MailRecipient, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var recipients []*MailRecipient
+	if err := dec.Decode(&recipients); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for i, recipient := range recipients {
+		if recipient == nil {
+			errs = append(errs, fmt.Errorf("recipient %d: missing", i))
+			continue
+		}
+		initRecipient(recipient)
+		if _, err := ParseEmail(recipient.Email); err != nil {
+			errs = append(errs, fmt.Errorf("recipient %d (%q): %w", i, recipient.Email, err))
+		}
+	}
+	return recipients, errors.Join(errs...)
+}
+
+// csvColumns maps CSV header names to the recipient field they set
+var csvColumns = map[string]func(r *MailRecipient, v string){
+	"email":            func(r *MailRecipient, v string) { r.Email = v },
+	"name":             func(r *MailRecipient, v string) { r.Name = v },
+	"first_name":       func(r *MailRecipient, v string) { r.FirstName = v },
+	"middle_name":      func(r *MailRecipient, v string) { r.MiddleName = v },
+	"last_name":        func(r *MailRecipient, v string) { r.LastName = v },
+	"gender":           func(r *MailRecipient, v string) { r.Gender = v },
+	"address1":         func(r *MailRecipient, v string) { r.Address1 = v },
+	"address2":         func(r *MailRecipient, v string) { r.Address2 = v },
+	"city":             func(r *MailRecipient, v string) { r.City = v },
+	"state":            func(r *MailRecipient, v string) { r.State = v },
+	"country":          func(r *MailRecipient, v string) { r.Country = v },
+	"postal_code":      func(r *MailRecipient, v string) { r.PostalCode = v },
+	"designation":      func(r *MailRecipient, v string) { r.Designation = v },
+	"company":          func(r *MailRecipient, v string) { r.Company = v },
+	"industry":         func(r *MailRecipient, v string) { r.Industry = v },
+	"description":      func(r *MailRecipient, v string) { r.Description = v },
+	"anniversary_date": func(r *MailRecipient, v string) { r.AnniversaryDate = v },
+}
+
+// RecipientsFromCSV reads recipients from CSV with a header row. Columns
+// named like the recipient JSON fields (email, first_name, last_name,
+// company, ...) set those fields, age must be an integer, and any other
+// column is stored in Attributes. The email column is required, and a row
+// without an email is reported with its line number.
+func RecipientsFromCSV(r io.Reader) ([]*MailRecipient, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	emailColumn := -1
+	for i, name := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(name))
+		if header[i] == "email" {
+			emailColumn = i
+		}
+	}
+	if emailColumn < 0 {
+		return nil, errors.New("CSV header has no email column")
+	}
+
+	var recipients []*MailRecipient
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		recipient := NewMailRecipient("", "")
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			column := header[i]
+			if set, ok := csvColumns[column]; ok {
+				set(recipient, value)
+				continue
+			}
+			if column == "age" {
+				age, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: age %q should be an integer", line, value)
+				}
+				recipient.Age = age
+				continue
+			}
+			recipient.Attributes[column] = value
+		}
+		if recipient.Email == "" {
+			return nil, fmt.Errorf("line %d: missing email", line)
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
+// initRecipient restores the empty slices and maps set by NewMailRecipient
+func initRecipient(r *MailRecipient) {
+	if r.Attributes == nil {
+		r.Attributes = make(map[string]interface{})
+	}
+	if r.Lists == nil {
+		r.Lists = make([]string, 0)
+	}
+	if r.Tags == nil {
+		r.Tags = make([]string, 0)
+	}
+}
+
+// SetFirstName sets the recipient's first name
+func (r *MailRecipient) SetFirstName(firstName string) *MailRecipient {
+	r.FirstName = firstName
+	return r
+}
+
+// SetLastName sets the recipient's last name
+func (r *MailRecipient) SetLastName(lastName string) *MailRecipient {
+	r.LastName = lastName
+	return r
+}
+
+// SetCompany sets the recipient's company
+func (r *MailRecipient) SetCompany(company string) *MailRecipient {
+	r.Company = company
+	return r
+}
+
+// SetCity sets the recipient's city
+func (r *MailRecipient) SetCity(city string) *MailRecipient {
+	r.City = city
+	return r
+}
+
+// SetCountry sets the recipient's country
+func (r *MailRecipient) SetCountry(country string) *MailRecipient {
+	r.Country = country
+	return r
+}
+
+// SetAttribute adds a custom attribute key/value
+func (r *MailRecipient) SetAttribute(key string, value interface{}) *MailRecipient {
+	if r.Attributes == nil {
+		r.Attributes = make(map[string]interface{})
+	}
+	r.Attributes[key] = value
+	return r
+}
+
+// SetSubstitution adds a substitution key/value, a template value for this
+// send only, such as a one-time code. Unlike attributes, substitutions are
+// not stored on the contact.
+func (r *MailRecipient) SetSubstitution(key string, value interface{}) *MailRecipient {
+	if r.Substitutions == nil {
+		r.Substitutions = make(map[string]interface{})
+	}
+	r.Substitutions[key] = value
+	return r
+}
+
+// AddTag appends tags to the recipient, skipping empty and already present ones
+func (r *MailRecipient) AddTag(tags ...string) *MailRecipient {
+	r.Tags = appendUnique(r.Tags, tags)
+	return r
+}
+
+// AddToList appends list IDs to the recipient, skipping empty and already present ones
+func (r *MailRecipient) AddToList(listIDs ...string) *MailRecipient {
+	r.Lists = appendUnique(r.Lists, listIDs)
+	return r
+}
+
+// appendUnique appends the non-empty values not already in dst
+func appendUnique(dst, values []string) []string {
+	seen := make(map[string]bool, len(dst)+len(values))
+	for _, v := range dst {
+		seen[v] = true
+	}
+	for _, v := range values {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		dst = append(dst, v)
+	}
+	return dst
+}
+
+// SetAnniversaryDate sets AnniversaryDate to the calendar date of t, in t's
+// location. A zero t clears it.
+func (r *MailRecipient) SetAnniversaryDate(t time.Time) *MailRecipient {
+	if t.IsZero() {
+		r.AnniversaryDate = ""
+		return r
+	}
+	r.AnniversaryDate = t.Format(AnniversaryDateLayout)
+	return r
+}
+
+// AnniversaryDateTime parses AnniversaryDate as a date at midnight UTC.
+// An empty AnniversaryDate returns the zero time and no error.
+func (r *MailRecipient) AnniversaryDateTime() (time.Time, error) {
+	if r.AnniversaryDate == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(AnniversaryDateLayout, r.AnniversaryDate)
+}
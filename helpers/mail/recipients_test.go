@@ -0,0 +1,194 @@
+package mail
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecipientsFromJSON(t *testing.T) {
+	f, err := os.Open("testdata/recipients.json")
+	assert.Nil(t, err)
+	defer f.Close()
+
+	recipients, err := RecipientsFromJSON(f)
+	assert.Nil(t, err)
+	assert.Len(t, recipients, 2)
+
+	jane := recipients[0]
+	assert.Equal(t, "jane@example.com", jane.Email)
+	assert.Equal(t, "Jane", jane.FirstName)
+	assert.Equal(t, json.Number("9007199254740993"), jane.Attributes["account_id"], "large numbers should keep their precision")
+	assert.Equal(t, []string{"newsletter"}, jane.Lists)
+	assert.NotNil(t, jane.Tags, "nil slices should be reinitialized")
+
+	john := recipients[1]
+	assert.NotNil(t, john.Attributes, "nil maps should be reinitialized")
+	assert.NotNil(t, john.Lists)
+	assert.Equal(t, []string{"vip"}, john.Tags)
+}
+
+func TestRecipientsFromJSONInvalidEmails(t *testing.T) {
+	input := `[{"email": "ok@example.com"}, {"email": "not-an-email"}, {"email": "also@example.com"}, {"email": ""}]`
+
+	recipients, err := RecipientsFromJSON(strings.NewReader(input))
+	assert.Len(t, recipients, 4, "all decoded recipients should be returned")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "recipient 1")
+	assert.Contains(t, err.Error(), "recipient 3")
+	assert.NotContains(t, err.Error(), "recipient 0")
+	assert.NotContains(t, err.Error(), "recipient 2")
+}
+
+func TestRecipientsFromJSONMalformed(t *testing.T) {
+	_, err := RecipientsFromJSON(strings.NewReader(`{"email": "ok@example.com"}`))
+	assert.NotNil(t, err, "a JSON object instead of an array should be rejected")
+}
+
+func TestRecipientsFromCSV(t *testing.T) {
+	input := `email,first_name,last_name,company,age,plan
+jane@example.com,Jane,Doe,Acme,34,pro
+john@example.com,John,,,,
+`
+	recipients, err := RecipientsFromCSV(strings.NewReader(input))
+	assert.Nil(t, err)
+	assert.Len(t, recipients, 2)
+
+	jane := recipients[0]
+	assert.Equal(t, "jane@example.com", jane.Email)
+	assert.Equal(t, "Jane", jane.FirstName)
+	assert.Equal(t, "Doe", jane.LastName)
+	assert.Equal(t, "Acme", jane.Company)
+	assert.Equal(t, 34, jane.Age)
+	assert.Equal(t, map[string]interface{}{"plan": "pro"}, jane.Attributes, "unknown columns should become attributes")
+
+	john := recipients[1]
+	assert.Equal(t, "John", john.FirstName)
+	assert.Empty(t, john.LastName)
+	assert.Empty(t, john.Attributes, "empty cells should be skipped")
+	assert.NotNil(t, john.Tags)
+}
+
+func TestRecipientsFromCSVMissingEmailColumn(t *testing.T) {
+	_, err := RecipientsFromCSV(strings.NewReader("first_name,last_name\nJane,Doe\n"))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "email column")
+}
+
+func TestRecipientsFromCSVMissingEmail(t *testing.T) {
+	input := "email,first_name\njane@example.com,Jane\n,John\n"
+	_, err := RecipientsFromCSV(strings.NewReader(input))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "line 3")
+}
+
+func TestRecipientsFromCSVInvalidAge(t *testing.T) {
+	_, err := RecipientsFromCSV(strings.NewReader("email,age\njane@example.com,old\n"))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
+
+func TestMailRecipientAddressJSON(t *testing.T) {
+	r := NewMailRecipient("Jane", "jane@example.com")
+	r.Address1 = "1 Main St"
+	r.Address2 = "Apt 2"
+	b, err := json.Marshal(r)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"address1":"1 Main St","address2":"Apt 2"`)
+
+	for _, stored := range []string{
+		`{"email":"jane@example.com","address1":"1 Main St","address2":"Apt 2"}`,
+		`{"email":"jane@example.com","Address1":"1 Main St","Address2":"Apt 2"}`,
+	} {
+		var decoded MailRecipient
+		assert.Nil(t, json.Unmarshal([]byte(stored), &decoded))
+		assert.Equal(t, "1 Main St", decoded.Address1, stored)
+		assert.Equal(t, "Apt 2", decoded.Address2, stored)
+	}
+}
+
+func TestMailRecipientSetters(t *testing.T) {
+	r := NewMailRecipient("Jane Doe", "jane@example.com").
+		SetFirstName("Jane").
+		SetLastName("Doe").
+		SetCompany("Acme").
+		SetCity("Lisbon").
+		SetCountry("PT").
+		SetAttribute("plan", "pro").
+		SetAttribute("seats", 5)
+
+	assert.Equal(t, &MailRecipient{
+		Email:      "jane@example.com",
+		Name:       "Jane Doe",
+		FirstName:  "Jane",
+		LastName:   "Doe",
+		Company:    "Acme",
+		City:       "Lisbon",
+		Country:    "PT",
+		Attributes: map[string]interface{}{"plan": "pro", "seats": 5},
+		Lists:      []string{},
+		Tags:       []string{},
+	}, r)
+
+	assert.Equal(t, "pro", (&MailRecipient{}).SetAttribute("plan", "pro").Attributes["plan"])
+}
+
+func TestMailRecipientAddTagAndList(t *testing.T) {
+	r := NewMailRecipient("Jane", "jane@example.com").
+		AddTag("vip", "", "beta").
+		AddTag("vip", "early").
+		AddToList("list-1", "list-1", "").
+		AddToList("list-2")
+
+	assert.Equal(t, []string{"vip", "beta", "early"}, r.Tags)
+	assert.Equal(t, []string{"list-1", "list-2"}, r.Lists)
+
+	empty := (&MailRecipient{}).AddTag("", "")
+	assert.Empty(t, empty.Tags)
+}
+
+func TestMailRecipientAnniversaryDate(t *testing.T) {
+	r := NewMailRecipient("Jane", "jane@example.com")
+	date, err := r.AnniversaryDateTime()
+	assert.Nil(t, err)
+	assert.True(t, date.IsZero())
+
+	r.SetAnniversaryDate(time.Date(2019, time.June, 1, 23, 30, 0, 0, time.FixedZone("PDT", -7*3600)))
+	assert.Equal(t, "2019-06-01", r.AnniversaryDate, "the date should be taken in the time's own location")
+	date, err = r.AnniversaryDateTime()
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2019, time.June, 1, 0, 0, 0, 0, time.UTC), date)
+	assert.Nil(t, r.Validate())
+
+	r.AnniversaryDate = "06/01/2019"
+	_, err = r.AnniversaryDateTime()
+	assert.NotNil(t, err)
+	err = r.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `anniversary_date "06/01/2019"`)
+	}
+
+	assert.Empty(t, r.SetAnniversaryDate(time.Time{}).AnniversaryDate)
+}
+
+func TestMailRecipientSubstitutions(t *testing.T) {
+	r := NewMailRecipient("Jane", "jane@example.com")
+	b, err := json.Marshal(r)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(b), "substitutions")
+	assert.NotContains(t, string(b), "attributes")
+
+	r.SetAttribute("plan", "pro").SetSubstitution("code", "123456")
+	b, err = json.Marshal(r)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"attributes":{"plan":"pro"}`)
+	assert.Contains(t, string(b), `"substitutions":{"code":"123456"}`)
+
+	clone := NewMailSendRequest().AddRecipient(r).Clone()
+	clone.To[0].SetSubstitution("code", "654321")
+	assert.Equal(t, "123456", r.Substitutions["code"], "Clone should copy substitutions")
+}
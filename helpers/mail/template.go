@@ -0,0 +1,45 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Template describes a transactional template, as returned by the
+// templates API of the cocoonmail package
+type Template struct {
+	ID                string   `json:"id"`
+	Name              string   `json:"name"`
+	RequiredVariables []string `json:"required_variables"`
+}
+
+// ValidateAgainstTemplate checks the request can fill template t: its
+// TransactionalID, if set, is t's ID, and every required variable is a key
+// of CustomParameter or of the Attributes or Substitutions of every To
+// recipient. Every problem found is reported in the returned error.
+func (m *MailSendRequest) ValidateAgainstTemplate(t *Template) error {
+	var errs []error
+	if m.TransactionalID != "" && m.TransactionalID != t.ID {
+		errs = append(errs, fmt.Errorf("transactional ID %q does not match template %q", m.TransactionalID, t.ID))
+	}
+	for _, variable := range t.RequiredVariables {
+		if _, ok := m.CustomParameter[variable]; ok {
+			continue
+		}
+		if len(m.To) == 0 {
+			errs = append(errs, fmt.Errorf("template variable %q: missing", variable))
+			continue
+		}
+		for i, r := range m.To {
+			if r == nil {
+				continue
+			}
+			_, isAttribute := r.Attributes[variable]
+			_, isSubstitution := r.Substitutions[variable]
+			if !isAttribute && !isSubstitution {
+				errs = append(errs, fmt.Errorf("template variable %q: missing for to[%d] %q", variable, i, r.Email))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAgainstTemplate(t *testing.T) {
+	tmpl := &Template{ID: "welcome", Name: "Welcome", RequiredVariables: []string{"company", "first_name"}}
+
+	m := NewMailSendRequest().
+		AddRecipient(NewMailRecipient("Jane", "jane@example.com").SetAttribute("first_name", "Jane")).
+		AddRecipient(NewMailRecipient("John", "john@example.com").SetAttribute("first_name", "John")).
+		SetCustomParameter("company", "Acme")
+	m.TransactionalID = "welcome"
+	assert.Nil(t, m.ValidateAgainstTemplate(tmpl))
+
+	delete(m.To[1].Attributes, "first_name")
+	delete(m.CustomParameter, "company")
+	err := m.ValidateAgainstTemplate(tmpl)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `template variable "company": missing for to[0] "jane@example.com"`)
+		assert.Contains(t, err.Error(), `template variable "first_name": missing for to[1] "john@example.com"`)
+		assert.NotContains(t, err.Error(), `"first_name": missing for to[0]`)
+	}
+
+	m.To[1].SetSubstitution("first_name", "John")
+	m.SetCustomParameter("company", "Acme")
+	assert.Nil(t, m.ValidateAgainstTemplate(tmpl), "a substitution should fill a required variable")
+
+	m.TransactionalID = "other"
+	err = m.ValidateAgainstTemplate(tmpl)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `transactional ID "other" does not match template "welcome"`)
+	}
+}
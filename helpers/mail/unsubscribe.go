@@ -0,0 +1,96 @@
+package mail
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UnsubscribeTokenTTL is how long a token built by BuildUnsubscribeURL stays valid
+var UnsubscribeTokenTTL = 30 * 24 * time.Hour
+
+// unsubscribeNow returns the current time; tests replace it.
+var unsubscribeNow = time.Now
+
+var (
+	// ErrInvalidUnsubscribeToken is returned for malformed or tampered tokens
+	ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+	// ErrExpiredUnsubscribeToken is returned for tokens past their expiry
+	ErrExpiredUnsubscribeToken = errors.New("expired unsubscribe token")
+)
+
+// BuildUnsubscribeURL appends a signed, expiring token for email to base as
+// the "token" query parameter. The landing page passes that parameter to
+// VerifyUnsubscribeToken to learn which address asked to unsubscribe.
+// The token is an HMAC-SHA256 signature over the email and expiry time,
+// encoded as URL-safe base64.
+func BuildUnsubscribeURL(base, email, secret string) (string, error) {
+	if secret == "" {
+		return "", errors.New("unsubscribe secret should not be empty")
+	}
+	if _, err := ParseEmail(email); err != nil {
+		return "", err
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	if !u.IsAbs() {
+		return "", fmt.Errorf("unsubscribe base URL %q should be absolute", base)
+	}
+
+	expires := unsubscribeNow().Add(UnsubscribeTokenTTL).Unix()
+	payload := email + "\n" + strconv.FormatInt(expires, 10)
+	token := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(signUnsubscribe(payload, secret))
+
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// VerifyUnsubscribeToken checks a token built by BuildUnsubscribeURL and
+// returns the email address it was issued for.
+func VerifyUnsubscribeToken(token, secret string) (string, error) {
+	encodedPayload, encodedSig, found := strings.Cut(token, ".")
+	if !found {
+		return "", ErrInvalidUnsubscribeToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", ErrInvalidUnsubscribeToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return "", ErrInvalidUnsubscribeToken
+	}
+	if !hmac.Equal(sig, signUnsubscribe(string(payload), secret)) {
+		return "", ErrInvalidUnsubscribeToken
+	}
+
+	email, expiresAt, found := strings.Cut(string(payload), "\n")
+	if !found {
+		return "", ErrInvalidUnsubscribeToken
+	}
+	expires, err := strconv.ParseInt(expiresAt, 10, 64)
+	if err != nil {
+		return "", ErrInvalidUnsubscribeToken
+	}
+	if unsubscribeNow().Unix() > expires {
+		return "", ErrExpiredUnsubscribeToken
+	}
+	return email, nil
+}
+
+func signUnsubscribe(payload, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload)) // nolint
+	return mac.Sum(nil)
+}
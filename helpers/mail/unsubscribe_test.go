@@ -0,0 +1,77 @@
+package mail
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildUnsubscribeURL(t *testing.T) {
+	link, err := BuildUnsubscribeURL("https://example.com/unsubscribe?list=news", "jane@example.com", "secret")
+	assert.Nil(t, err)
+
+	u, err := url.Parse(link)
+	assert.Nil(t, err)
+	assert.Equal(t, "example.com", u.Host)
+	assert.Equal(t, "news", u.Query().Get("list"), "existing query parameters should be kept")
+
+	token := u.Query().Get("token")
+	assert.NotEmpty(t, token)
+	assert.NotContains(t, token, "+", "token should be URL-safe")
+	assert.NotContains(t, token, "/", "token should be URL-safe")
+
+	email, err := VerifyUnsubscribeToken(token, "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, "jane@example.com", email)
+}
+
+func TestBuildUnsubscribeURLInvalidInput(t *testing.T) {
+	_, err := BuildUnsubscribeURL("https://example.com/u", "not-an-email", "secret")
+	assert.NotNil(t, err)
+	_, err = BuildUnsubscribeURL("/relative", "jane@example.com", "secret")
+	assert.NotNil(t, err)
+	_, err = BuildUnsubscribeURL("https://example.com/u", "jane@example.com", "")
+	assert.NotNil(t, err)
+}
+
+func TestVerifyUnsubscribeTokenTampered(t *testing.T) {
+	link, _ := BuildUnsubscribeURL("https://example.com/u", "jane@example.com", "secret")
+	u, _ := url.Parse(link)
+	token := u.Query().Get("token")
+
+	_, err := VerifyUnsubscribeToken(token, "other-secret")
+	assert.Equal(t, ErrInvalidUnsubscribeToken, err)
+
+	forged, _ := BuildUnsubscribeURL("https://example.com/u", "john@example.com", "secret")
+	fu, _ := url.Parse(forged)
+	payload := strings.Split(fu.Query().Get("token"), ".")[0]
+	signature := strings.Split(token, ".")[1]
+	_, err = VerifyUnsubscribeToken(payload+"."+signature, "secret")
+	assert.Equal(t, ErrInvalidUnsubscribeToken, err, "a signature should not be reusable for another address")
+
+	for _, bad := range []string{"", "no-dot", "!!!.!!!", "abc.def"} {
+		_, err = VerifyUnsubscribeToken(bad, "secret")
+		assert.Equal(t, ErrInvalidUnsubscribeToken, err)
+	}
+}
+
+func TestVerifyUnsubscribeTokenExpired(t *testing.T) {
+	issued := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	unsubscribeNow = func() time.Time { return issued }
+	defer func() { unsubscribeNow = time.Now }()
+
+	link, _ := BuildUnsubscribeURL("https://example.com/u", "jane@example.com", "secret")
+	u, _ := url.Parse(link)
+	token := u.Query().Get("token")
+
+	unsubscribeNow = func() time.Time { return issued.Add(UnsubscribeTokenTTL - time.Minute) }
+	_, err := VerifyUnsubscribeToken(token, "secret")
+	assert.Nil(t, err)
+
+	unsubscribeNow = func() time.Time { return issued.Add(UnsubscribeTokenTTL + time.Minute) }
+	_, err = VerifyUnsubscribeToken(token, "secret")
+	assert.Equal(t, ErrExpiredUnsubscribeToken, err)
+}
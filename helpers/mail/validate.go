@@ -0,0 +1,404 @@
+package mail
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Tag limits applied by MailRecipient.Validate. Change them before
+// validating if your account has different limits.
+var (
+	MaxRecipientTags      = 10
+	MaxRecipientTagLength = 64
+)
+
+// MaxPayloadBytes is the largest marshaled request Validate accepts. The
+// default leaves room for DefaultMaxTotalAttachmentBytes of attachments
+// once base64-encoded.
+var MaxPayloadBytes = 40 * 1024 * 1024
+
+// MaxBodyBytes is the largest HTMLContent or TextContent Validate accepts,
+// to catch a huge blob pasted into a body rather than attached. It applies
+// to each body on its own, separately from the attachment limits.
+var MaxBodyBytes = 2 * 1024 * 1024
+
+// ErrDuplicateAcrossLists is wrapped by the Validate error when an address
+// is in more than one of To, Cc and Bcc. Check it with errors.Is to treat it
+// as a warning, or turn the check off with AllowDuplicateAcrossLists.
+var ErrDuplicateAcrossLists = errors.New("address in more than one of to, cc and bcc")
+
+// minRecipientAge and maxRecipientAge bound a set Age in MailRecipient.Validate
+const (
+	minRecipientAge = 1
+	maxRecipientAge = 150
+)
+
+// Validate checks the request for problems the API would reject:
+//   - no To recipients, or invalid recipient or Reply-To addresses
+//   - recipients failing MailRecipient.Validate
+//   - addresses in more than one of To, Cc and Bcc, see
+//     AllowDuplicateAcrossLists
+//   - a ScheduledAt that is not RFC3339 or is in the past
+//   - a malformed TransactionalID, ConversationID or ContentLanguage
+//   - an HTML or text body larger than MaxBodyBytes
+//   - attachments failing MailAttachment.Validate or the
+//     SetAttachmentContentTypePolicy policy, which rejects executables
+//     even when no policy is set
+//   - attachments over the total size limit
+//   - custom parameters that cannot be marshaled to JSON
+//   - a body larger than MaxPayloadBytes
+//
+// Every problem found is reported in the returned error. Attachments added
+// both inline and remote are only a warning, not checked here; see
+// DuplicateAttachments.
+func (m *MailSendRequest) Validate() error {
+	var errs []error
+
+	if len(m.To) == 0 {
+		errs = append(errs, errors.New("at least one recipient is required"))
+	}
+	errs = append(errs, validateRecipients("to", m.To)...)
+	errs = append(errs, validateRecipients("cc", m.Cc)...)
+	errs = append(errs, validateRecipients("bcc", m.Bcc)...)
+	if !m.allowDuplicateAcrossLists {
+		if dups := m.duplicatesAcrossLists(); len(dups) > 0 {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrDuplicateAcrossLists, strings.Join(dups, ", ")))
+		}
+	}
+
+	if m.ReplyTo != "" {
+		if _, err := ParseEmail(m.ReplyTo); err != nil {
+			errs = append(errs, fmt.Errorf("reply_to %q: %w", m.ReplyTo, err))
+		}
+	}
+	if err := m.validateSchedule(); err != nil {
+		errs = append(errs, err)
+	}
+	if m.TransactionalID != "" {
+		if err := validateTransactionalID(m.TransactionalID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if m.ConversationID != "" {
+		if err := validateConversationID(m.ConversationID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if m.ContentLanguage != "" {
+		if err := validateLanguageTag(m.ContentLanguage); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, body := range []struct{ field, content string }{
+		{"html", m.HTMLContent},
+		{"text", m.TextContent},
+	} {
+		if n := len(body.content); n > MaxBodyBytes {
+			errs = append(errs, fmt.Errorf("%s body is %d bytes, %d over the limit of %d bytes", body.field, n, n-MaxBodyBytes, MaxBodyBytes))
+		}
+	}
+
+	for i, a := range m.Attachments {
+		if a == nil {
+			errs = append(errs, fmt.Errorf("attachments[%d]: missing attachment", i))
+			continue
+		}
+		if err := a.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("attachments[%d] %q: %w", i, a.Filename, err))
+		}
+		if err := checkAttachmentPolicy(a, m.allowedContentTypes, m.blockedContentTypes); err != nil {
+			errs = append(errs, fmt.Errorf("attachments[%d] %q: %w", i, a.Filename, err))
+		}
+	}
+	if err := m.validateAttachmentSize(); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, m.validateCustomParameters()...)
+	if size, err := m.estimatedSizeOfValidValues(); err != nil {
+		errs = append(errs, err)
+	} else if size > MaxPayloadBytes {
+		errs = append(errs, fmt.Errorf("request is %d bytes, exceeding the limit of %d bytes", size, MaxPayloadBytes))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateSchedule checks that ScheduledAt, if set, is an RFC3339 timestamp
+// no more than scheduleGracePeriod in the past
+func (m *MailSendRequest) validateSchedule() error {
+	if m.ScheduledAt == "" {
+		return nil
+	}
+	at, err := time.Parse(time.RFC3339, m.ScheduledAt)
+	if err != nil {
+		return fmt.Errorf("scheduled_at %q: should be an RFC3339 timestamp", m.ScheduledAt)
+	}
+	now := time.Now
+	if m.now != nil {
+		now = m.now
+	}
+	if at.Before(now().Add(-scheduleGracePeriod)) {
+		return fmt.Errorf("scheduled_at %q: is in the past", m.ScheduledAt)
+	}
+	return nil
+}
+
+// validateCustomParameters checks that every custom parameter value can be
+// marshaled to JSON, reporting bad keys in sorted order
+func (m *MailSendRequest) validateCustomParameters() []error {
+	return validateJSONValues("custom_parameter", m.CustomParameter)
+}
+
+// validateJSONValues checks that every value of a map field can be
+// marshaled to JSON, reporting bad keys in sorted order
+func validateJSONValues(field string, values map[string]interface{}) []error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var errs []error
+	for _, key := range keys {
+		if _, err := json.Marshal(values[key]); err != nil {
+			errs = append(errs, fmt.Errorf("%s %q: %w", field, key, err))
+		}
+	}
+	return errs
+}
+
+// estimatedSizeOfValidValues returns the EstimatedSize of a clone of m
+// without the custom parameter, attribute and substitution values that
+// cannot be marshaled to JSON. Validate reports those by key, so a marshal
+// error left here comes from another field.
+func (m *MailSendRequest) estimatedSizeOfValidValues() (int, error) {
+	c := m.Clone()
+	dropInvalidJSONValues(c.CustomParameter)
+	for _, recipients := range [][]*MailRecipient{c.To, c.Cc, c.Bcc} {
+		for _, r := range recipients {
+			if r != nil {
+				dropInvalidJSONValues(r.Attributes)
+				dropInvalidJSONValues(r.Substitutions)
+			}
+		}
+	}
+	return c.EstimatedSize()
+}
+
+// dropInvalidJSONValues deletes the values that cannot be marshaled to JSON
+func dropInvalidJSONValues(values map[string]interface{}) {
+	for key, value := range values {
+		if _, err := json.Marshal(value); err != nil {
+			delete(values, key)
+		}
+	}
+}
+
+// validateAttachmentSize checks the decoded size of all inline attachments
+func (m *MailSendRequest) validateAttachmentSize() error {
+	limit := m.maxTotalAttachmentBytes
+	if limit <= 0 {
+		limit = DefaultMaxTotalAttachmentBytes
+	}
+	var total int64
+	for _, a := range m.Attachments {
+		total += int64(attachmentSize(a))
+	}
+	if total > limit {
+		return fmt.Errorf("attachments total %d bytes, exceeding the limit of %d bytes", total, limit)
+	}
+	return nil
+}
+
+// validateRecipients checks the email of every recipient of one field
+func validateRecipients(field string, recipients []*MailRecipient) []error {
+	var errs []error
+	for i, r := range recipients {
+		if r == nil {
+			errs = append(errs, fmt.Errorf("%s[%d]: missing recipient", field, i))
+			continue
+		}
+		if _, err := ParseEmail(r.Email); err != nil {
+			errs = append(errs, fmt.Errorf("%s[%d] %q: %w", field, i, r.Email, err))
+		}
+		if err := r.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s[%d] %q: %w", field, i, r.Email, err))
+		}
+	}
+	return errs
+}
+
+// Validate checks the recipient fields other than Email: at most
+// MaxRecipientTags tags of at most MaxRecipientTagLength characters each,
+// an Age between 1 and 150 unless it is zero, which means unset, an
+// AnniversaryDate in the AnniversaryDateLayout format when set, and
+// Attributes and Substitutions values that can be marshaled to JSON.
+// Every problem found is reported in the returned error.
+func (r *MailRecipient) Validate() error {
+	var errs []error
+	if r.Age != 0 && (r.Age < minRecipientAge || r.Age > maxRecipientAge) {
+		errs = append(errs, fmt.Errorf("age %d: should be between %d and %d, or 0 when unset", r.Age, minRecipientAge, maxRecipientAge))
+	}
+	if _, err := r.AnniversaryDateTime(); err != nil {
+		errs = append(errs, fmt.Errorf("anniversary_date %q: should be a date formatted as %s", r.AnniversaryDate, AnniversaryDateLayout))
+	}
+	if len(r.Tags) > MaxRecipientTags {
+		errs = append(errs, fmt.Errorf("has %d tags, exceeding the limit of %d", len(r.Tags), MaxRecipientTags))
+	}
+	for _, tag := range r.Tags {
+		if n := utf8.RuneCountInString(tag); n > MaxRecipientTagLength {
+			errs = append(errs, fmt.Errorf("tag %q is %d characters, exceeding the limit of %d", tag, n, MaxRecipientTagLength))
+		}
+	}
+	errs = append(errs, validateJSONValues("attributes", r.Attributes)...)
+	errs = append(errs, validateJSONValues("substitutions", r.Substitutions)...)
+	return errors.Join(errs...)
+}
+
+// Validate checks that Data is valid standard base64 and that an inline
+// attachment has a ContentID. Every problem found is reported in the
+// returned error.
+func (a *MailAttachment) Validate() error {
+	var errs []error
+	if _, err := base64.StdEncoding.DecodeString(a.Data); err != nil {
+		errs = append(errs, fmt.Errorf("data is not valid base64: %w", err))
+	}
+	if a.Disposition == DispositionInline && a.ContentID == "" {
+		errs = append(errs, errors.New("inline attachment requires a content ID"))
+	}
+	return errors.Join(errs...)
+}
+
+// DefaultBlockedContentTypes are the executable media types an attachment
+// policy rejects unless they are explicitly allowed
+var DefaultBlockedContentTypes = []string{
+	"application/x-msdownload",
+	"application/x-msdos-program",
+	"application/x-dosexec",
+	"application/x-msi",
+	"application/vnd.microsoft.portable-executable",
+}
+
+// DefaultBlockedExtensions are the executable filename extensions an
+// attachment policy rejects unless their content type is explicitly allowed
+var DefaultBlockedExtensions = []string{".bat", ".cmd", ".com", ".dll", ".exe", ".msi", ".pif", ".ps1", ".scr", ".vbs"}
+
+// SetAttachmentContentTypePolicy restricts the content types of inline
+// attachments, enforced by Validate. Entries are media types such as
+// "application/pdf", or "image/*" for a whole top-level type, matched
+// case-insensitively and ignoring parameters. Rules apply in this order:
+//   - a type in block is always rejected, even if it is in allow
+//   - a type in allow is accepted
+//   - executables, a type in DefaultBlockedContentTypes or a filename with
+//     one of DefaultBlockedExtensions, are rejected
+//   - if allow is not empty, any other type is rejected too
+//
+// Executables are rejected even when no policy is set; list their type in
+// allow to accept them.
+func (m *MailSendRequest) SetAttachmentContentTypePolicy(allow []string, block []string) *MailSendRequest {
+	m.allowedContentTypes = append([]string(nil), allow...)
+	m.blockedContentTypes = append([]string(nil), block...)
+	return m
+}
+
+// CheckAttachmentPolicy checks the inline attachments against the allow and
+// deny lists, as SetAttachmentContentTypePolicy describes for its allow and
+// block lists, and returns an error naming the first rejected one. The
+// cocoonmail client calls it before sending with the policy set by
+// WithAttachmentMIMEPolicy.
+func (m *MailSendRequest) CheckAttachmentPolicy(allow, deny []string) error {
+	for i, a := range m.Attachments {
+		if a == nil {
+			continue
+		}
+		if err := checkAttachmentPolicy(a, allow, deny); err != nil {
+			return fmt.Errorf("attachments[%d] %q: %w", i, a.Filename, err)
+		}
+	}
+	return nil
+}
+
+// checkAttachmentPolicy checks one attachment against allow and deny lists
+func checkAttachmentPolicy(a *MailAttachment, allow, deny []string) error {
+	mediaType := strings.ToLower(strings.TrimSpace(a.ContentType))
+	if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+		mediaType = strings.TrimSpace(mediaType[:i])
+	}
+	if matchContentType(deny, mediaType) {
+		return fmt.Errorf("content type %q is blocked", a.ContentType)
+	}
+	if matchContentType(allow, mediaType) {
+		return nil
+	}
+	if matchContentType(DefaultBlockedContentTypes, mediaType) || hasBlockedExtension(a.Filename) {
+		return fmt.Errorf("content type %q: executables are blocked unless allowed", a.ContentType)
+	}
+	if len(allow) > 0 {
+		return fmt.Errorf("content type %q is not allowed", a.ContentType)
+	}
+	return nil
+}
+
+// hasBlockedExtension reports whether filename ends in one of
+// DefaultBlockedExtensions
+func hasBlockedExtension(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(strings.TrimSpace(filename)))
+	for _, blocked := range DefaultBlockedExtensions {
+		if ext == blocked {
+			return true
+		}
+	}
+	return false
+}
+
+// matchContentType reports whether mediaType matches one of patterns
+func matchContentType(patterns []string, mediaType string) bool {
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(p, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowDuplicateAcrossLists turns off the Validate check for an address in
+// more than one of To, Cc and Bcc, for senders who want it that way.
+func (m *MailSendRequest) AllowDuplicateAcrossLists(allow bool) *MailSendRequest {
+	m.allowDuplicateAcrossLists = allow
+	return m
+}
+
+// duplicatesAcrossLists returns the addresses, compared case-insensitively,
+// that appear in more than one of To, Cc and Bcc, in order of first appearance
+func (m *MailSendRequest) duplicatesAcrossLists() []string {
+	lists := make(map[string]int) // address => index of the first list it is in
+	reported := make(map[string]bool)
+	var dups []string
+	for i, recipients := range [][]*MailRecipient{m.To, m.Cc, m.Bcc} {
+		for _, r := range recipients {
+			if r == nil || r.Email == "" {
+				continue
+			}
+			key := strings.ToLower(strings.TrimSpace(r.Email))
+			first, seen := lists[key]
+			if !seen {
+				lists[key] = i
+			} else if first != i && !reported[key] {
+				reported[key] = true
+				dups = append(dups, r.Email)
+			}
+		}
+	}
+	return dups
+}
@@ -0,0 +1,362 @@
+package mail
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// validRequest returns a request that passes Validate
+func validRequest() *MailSendRequest {
+	return NewMailSendRequest().
+		AddRecipient(NewMailRecipient("Jane", "jane@example.com")).
+		AddCc(NewMailRecipient("John", "john@example.com")).
+		SetReplyTo("support@example.com").
+		SetScheduledAtTime(time.Now().Add(time.Hour))
+}
+
+func TestValidateValidRequest(t *testing.T) {
+	assert.Nil(t, validRequest().Validate())
+}
+
+func TestValidateNoRecipients(t *testing.T) {
+	err := NewMailSendRequest().Validate()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "at least one recipient")
+}
+
+func TestValidateInvalidRecipient(t *testing.T) {
+	m := validRequest().
+		AddRecipient(NewMailRecipient("Bad", "not-an-email")).
+		AddBcc(NewMailRecipient("Long", strings.Repeat("a", 65)+"@example.com"))
+	err := m.Validate()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), `to[1] "not-an-email"`)
+	assert.Contains(t, err.Error(), "bcc[0]")
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr), "ParseEmail errors should be wrapped")
+}
+
+func TestValidateInvalidReplyTo(t *testing.T) {
+	err := validRequest().SetReplyTo("support").Validate()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "reply_to")
+}
+
+func TestValidateInvalidScheduledAt(t *testing.T) {
+	err := validRequest().SetScheduledAt("tomorrow at noon").Validate()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "scheduled_at")
+}
+
+func TestValidateInvalidConversationAndLanguage(t *testing.T) {
+	m := validRequest()
+	m.ConversationID = "has space"
+	m.ContentLanguage = "en_US"
+	err := m.Validate()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "conversation ID")
+	assert.Contains(t, err.Error(), "language tag")
+}
+
+func TestValidateReportsAllFailures(t *testing.T) {
+	m := NewMailSendRequest().SetReplyTo("support").SetScheduledAt("soon")
+	err := m.Validate()
+	assert.NotNil(t, err)
+	msg := err.Error()
+	assert.Contains(t, msg, "at least one recipient")
+	assert.Contains(t, msg, "reply_to")
+	assert.Contains(t, msg, "scheduled_at")
+}
+
+func TestValidateAttachmentSize(t *testing.T) {
+	data := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", 6)))
+	m := validRequest().
+		AddAttachment(NewMailAttachment("a.txt", "text/plain", data), NewMailAttachment("b.txt", "text/plain", data)).
+		SetMaxTotalAttachmentBytes(12)
+	assert.Nil(t, m.Validate(), "attachments at the limit should be accepted")
+
+	m.SetMaxTotalAttachmentBytes(11)
+	err := m.Validate()
+	assert.NotNil(t, err, "attachments over the limit should be rejected")
+	assert.Contains(t, err.Error(), "12 bytes")
+
+	m.SetMaxTotalAttachmentBytes(0)
+	assert.Nil(t, m.Validate(), "the default limit should apply")
+}
+
+func TestValidateDefaultAttachmentSize(t *testing.T) {
+	under := make([]byte, DefaultMaxTotalAttachmentBytes)
+	m := validRequest().AddAttachment(NewMailAttachment("big.bin", "application/octet-stream", base64.StdEncoding.EncodeToString(under)))
+	assert.Nil(t, m.Validate())
+
+	m.AddAttachment(NewMailAttachment("one-more.bin", "application/octet-stream", base64.StdEncoding.EncodeToString([]byte{1})))
+	assert.NotNil(t, m.Validate())
+}
+
+func TestValidateSchedule(t *testing.T) {
+	now := time.Date(2030, 1, 2, 15, 0, 0, 0, time.UTC)
+	m := validRequest()
+	m.now = func() time.Time { return now }
+
+	m.SetScheduledAtTime(now.Add(-2 * time.Minute))
+	err := m.Validate()
+	assert.NotNil(t, err, "a past timestamp should be rejected")
+	assert.Contains(t, err.Error(), "in the past")
+
+	m.SetScheduledAtTime(now.Add(-30 * time.Second))
+	assert.Nil(t, m.Validate(), "a timestamp within the grace period should be accepted")
+
+	m.SetScheduledAtTime(now.Add(24 * time.Hour))
+	assert.Nil(t, m.Validate(), "a future timestamp should be accepted")
+}
+
+func TestRecipientValidateTags(t *testing.T) {
+	r := NewMailRecipient("Jane", "jane@example.com")
+	for i := 0; i < MaxRecipientTags; i++ {
+		r.Tags = append(r.Tags, fmt.Sprintf("tag-%d", i))
+	}
+	r.Tags[0] = strings.Repeat("t", MaxRecipientTagLength)
+	assert.Nil(t, r.Validate(), "tags at the limits should pass")
+
+	r.Tags = append(r.Tags, "one-too-many")
+	r.Tags[0] += "t"
+	err := r.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "has 11 tags, exceeding the limit of 10")
+		assert.Contains(t, err.Error(), "is 65 characters, exceeding the limit of 64")
+	}
+
+	m := validRequest().AddBcc(r)
+	err = m.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `bcc[0] "jane@example.com": has 11 tags`)
+	}
+}
+
+func TestRecipientValidateTagLimitsConfigurable(t *testing.T) {
+	previous := MaxRecipientTags
+	MaxRecipientTags = 1
+	t.Cleanup(func() { MaxRecipientTags = previous })
+
+	r := NewMailRecipient("Jane", "jane@example.com")
+	r.Tags = []string{"a"}
+	assert.Nil(t, r.Validate())
+	r.Tags = append(r.Tags, "b")
+	assert.NotNil(t, r.Validate())
+}
+
+func TestRecipientValidateAge(t *testing.T) {
+	r := NewMailRecipient("Jane", "jane@example.com")
+	for _, age := range []int{0, 1, 42, 150} {
+		r.Age = age
+		assert.Nil(t, r.Validate(), "age %d should be accepted", age)
+	}
+	for _, age := range []int{-1, 151, 9000} {
+		r.Age = age
+		err := r.Validate()
+		if assert.NotNil(t, err, "age %d should be rejected", age) {
+			assert.Contains(t, err.Error(), fmt.Sprintf("age %d: should be between 1 and 150, or 0 when unset", age))
+		}
+	}
+}
+
+func TestEstimatedSize(t *testing.T) {
+	m := validRequest().AddAttachment(NewMailAttachment("a.bin", "application/octet-stream", strings.Repeat("QUFB", 1000)))
+	size, err := m.EstimatedSize()
+	assert.Nil(t, err)
+	assert.Equal(t, len(GetRequestBody(m)), size)
+	assert.True(t, size > 4000, "the estimate should include attachments")
+
+	m.SetCustomParameter("bad", make(chan int))
+	_, err = m.EstimatedSize()
+	assert.NotNil(t, err)
+}
+
+func TestValidateMaxPayloadBytes(t *testing.T) {
+	m := validRequest()
+	size, _ := m.EstimatedSize()
+
+	previous := MaxPayloadBytes
+	t.Cleanup(func() { MaxPayloadBytes = previous })
+	MaxPayloadBytes = size
+	assert.Nil(t, m.Validate(), "a request at the limit should pass")
+
+	MaxPayloadBytes = size - 1
+	err := m.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), fmt.Sprintf("request is %d bytes, exceeding the limit of %d bytes", size, size-1))
+	}
+}
+
+func TestValidateMaxPayloadBytesWithInvalidValues(t *testing.T) {
+	m := validRequest()
+	size, _ := m.EstimatedSize()
+	previous := MaxPayloadBytes
+	t.Cleanup(func() { MaxPayloadBytes = previous })
+	MaxPayloadBytes = size - 1
+
+	m.SetCustomParameter("callback", make(chan int))
+	err := m.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `custom_parameter "callback"`)
+		assert.Contains(t, err.Error(), "exceeding the limit", "the size should still be checked without the invalid values")
+		assert.Equal(t, 1, strings.Count(err.Error(), "unsupported type"), "the marshal error should be reported once")
+	}
+	assert.Contains(t, m.CustomParameter, "callback", "Validate should not change the request")
+}
+
+func TestValidateMaxBodyBytes(t *testing.T) {
+	previous := MaxBodyBytes
+	t.Cleanup(func() { MaxBodyBytes = previous })
+	MaxBodyBytes = 10
+
+	m := validRequest().SetHTMLContent("<p>hello</p>").SetTextContent("0123456789")
+	err := m.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "html body is 12 bytes, 2 over the limit of 10 bytes")
+		assert.NotContains(t, err.Error(), "text body", "a body at the limit should pass")
+	}
+
+	MaxBodyBytes = previous
+	m.SetTextContent(strings.Repeat("x", 2*1024*1024+1))
+	err = m.Validate()
+	if assert.NotNil(t, err, "the default limit should reject an oversized body") {
+		assert.Contains(t, err.Error(), "text body is 2097153 bytes, 1 over the limit")
+	}
+}
+
+func TestValidateDuplicateAcrossLists(t *testing.T) {
+	m := validRequest().
+		AddCc(NewMailRecipient("Jane", "Jane@Example.com")).
+		AddRecipient(NewMailRecipient("Ann", "ann@example.com")).
+		AddRecipient(NewMailRecipient("Ann", "ann@example.com"))
+	err := m.Validate()
+	if assert.NotNil(t, err) {
+		assert.True(t, errors.Is(err, ErrDuplicateAcrossLists))
+		assert.Contains(t, err.Error(), "address in more than one of to, cc and bcc: Jane@Example.com")
+		assert.NotContains(t, err.Error(), "ann@example.com", "repeats within one list are not rejected")
+	}
+
+	assert.Nil(t, m.AllowDuplicateAcrossLists(true).Validate())
+}
+
+func TestAttachmentValidateBase64(t *testing.T) {
+	valid := NewMailAttachment("a.bin", "application/octet-stream", base64.StdEncoding.EncodeToString([]byte{0, 1, 2, 0xff}))
+	assert.Nil(t, valid.Validate())
+
+	raw := NewMailAttachment("raw.bin", "application/octet-stream", string([]byte{0x89, 'P', 'N', 'G', 0, 0xff}))
+	err := raw.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "data is not valid base64")
+	}
+
+	err = validRequest().AddAttachment(valid).AddAttachment(raw).AddAttachment(nil).Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `attachments[1] "raw.bin": data is not valid base64`)
+		assert.Contains(t, err.Error(), "attachments[2]: missing attachment")
+		assert.NotContains(t, err.Error(), "attachments[0]")
+	}
+}
+
+func TestAttachmentContentTypePolicy(t *testing.T) {
+	exe := NewMailAttachment("setup.exe", "application/x-msdownload", "TVo=")
+	pdf := NewMailAttachment("invoice.pdf", "application/pdf", "JVBE")
+	png := NewMailAttachment("logo.png", "image/PNG; name=logo.png", "iVBO")
+
+	m := validRequest().AddAttachment(exe).AddAttachment(pdf).AddAttachment(png)
+	err := m.Validate()
+	if assert.NotNil(t, err, "executables should be blocked without a policy") {
+		assert.Contains(t, err.Error(), `attachments[0] "setup.exe": content type "application/x-msdownload": executables are blocked unless allowed`)
+		assert.NotContains(t, err.Error(), "attachments[1]", "no policy should allow other types")
+		assert.NotContains(t, err.Error(), "attachments[2]")
+	}
+	m.SetAttachmentContentTypePolicy([]string{"application/x-msdownload", "application/pdf", "image/*"}, nil)
+	assert.Nil(t, m.Validate(), "an explicitly allowed executable should pass")
+
+	m.SetAttachmentContentTypePolicy([]string{"application/x-msdownload"}, []string{"application/x-msdownload"})
+	err = m.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `attachments[0] "setup.exe": content type "application/x-msdownload" is blocked`, "block should win over allow")
+		assert.Contains(t, err.Error(), `attachments[1] "invoice.pdf": content type "application/pdf" is not allowed`)
+	}
+
+	m.SetAttachmentContentTypePolicy([]string{"application/pdf", "image/*"}, nil)
+	err = m.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `attachments[0] "setup.exe": content type "application/x-msdownload": executables are blocked unless allowed`)
+		assert.NotContains(t, err.Error(), "attachments[1]")
+		assert.NotContains(t, err.Error(), "attachments[2]", "wildcards and parameters should match")
+	}
+
+	m.SetAttachmentContentTypePolicy([]string{"image/*"}, []string{"image/png"})
+	err = m.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `attachments[2] "logo.png": content type "image/PNG; name=logo.png" is blocked`)
+	}
+}
+
+func TestCheckAttachmentPolicy(t *testing.T) {
+	m := validRequest().
+		AddAttachment(NewMailAttachment("invoice.pdf", "application/pdf", "JVBE")).
+		AddAttachment(NewMailAttachment("Setup.EXE", "application/octet-stream", "TVo=")).
+		AddAttachment(NewMailAttachment("run.bat", "text/plain", "QA=="))
+	err := m.CheckAttachmentPolicy(nil, nil)
+	if assert.NotNil(t, err, "executable extensions should be blocked by default") {
+		assert.Equal(t, `attachments[1] "Setup.EXE": content type "application/octet-stream": executables are blocked unless allowed`, err.Error(), "only the first should be reported")
+	}
+	assert.Nil(t, m.CheckAttachmentPolicy([]string{"application/*", "text/plain"}, nil))
+	err = m.CheckAttachmentPolicy([]string{"application/*", "text/plain"}, []string{"application/pdf"})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `attachments[0] "invoice.pdf": content type "application/pdf" is blocked`)
+	}
+}
+
+func TestValidateCustomParameters(t *testing.T) {
+	m := validRequest().
+		SetCustomParameter("order", map[string]interface{}{"id": 42, "items": []string{"a", "b"}, "meta": map[string]bool{"gift": true}})
+	assert.Nil(t, m.Validate(), "a nested map should serialize fine")
+
+	m.SetCustomParameter("callback", make(chan int))
+	err := m.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `custom_parameter "callback": json: unsupported type: chan int`)
+		assert.Equal(t, 1, strings.Count(err.Error(), "unsupported type"), "the marshal error should be reported once")
+	}
+}
+
+func TestValidateRecipientValues(t *testing.T) {
+	m := validRequest()
+	m.To[0].SetAttribute("callback", make(chan int))
+	m.Cc[0].SetSubstitution("ratio", math.NaN())
+	err := m.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `to[0] "jane@example.com": attributes "callback": json: unsupported type: chan int`)
+		assert.Contains(t, err.Error(), `cc[0] "john@example.com": substitutions "ratio": json: unsupported value: NaN`)
+		assert.Equal(t, 1, strings.Count(err.Error(), "unsupported type"), "the marshal error should be reported once")
+	}
+
+	m = validRequest().SetCustomParameter("callback", make(chan int))
+	m.To[0].SetAttribute("ratio", math.Inf(1))
+	err = m.Validate()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, 1, strings.Count(err.Error(), "unsupported type"))
+		assert.Equal(t, 1, strings.Count(err.Error(), "unsupported value"))
+	}
+}
+
+func TestValidateDuplicateAttachments(t *testing.T) {
+	m := validRequest().
+		AddAttachment(NewMailAttachment("report.pdf", "application/pdf", "ZGF0YQ==")).
+		AddRemoteAttachment(NewMailAttachmentRemote("https://cdn.example.com/files/report.pdf"))
+	assert.Nil(t, m.Validate(), "duplicate attachments are a warning, not a Validate error")
+	assert.Equal(t, []string{"report.pdf"}, m.DuplicateAttachments())
+	assert.Empty(t, m.DropInlineDuplicates().DuplicateAttachments())
+}
@@ -0,0 +1,54 @@
+package cocoonmail
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// List is a contact list
+type List struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	ContactCount int    `json:"contact_count"`
+}
+
+// ListsClient manages contact lists. It shares the options, headers and
+// data residency handling of Client.
+type ListsClient struct {
+	client *Client
+}
+
+// NewListsClient constructs a client for the contact lists API given an API key and options
+func NewListsClient(key string, opts ...ClientOption) *ListsClient {
+	return &ListsClient{client: newClient(key, "/webhook/lists", opts)}
+}
+
+// SetDataResidency sends the requests of c to the given region, as
+// SetDataResidency does for a request
+func (c *ListsClient) SetDataResidency(region string) error {
+	return c.client.setDataResidency(region)
+}
+
+// CreateList creates a list with the given name
+func (c *ListsClient) CreateList(ctx context.Context, name string) (*List, error) {
+	var list List
+	if err := c.client.doJSON(ctx, http.MethodPost, "", map[string]string{"name": name}, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetList returns the list with the given ID
+func (c *ListsClient) GetList(ctx context.Context, id string) (*List, error) {
+	var list List
+	if err := c.client.doJSON(ctx, http.MethodGet, "/"+url.PathEscape(id), nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// DeleteList deletes the list with the given ID
+func (c *ListsClient) DeleteList(ctx context.Context, id string) error {
+	return c.client.doJSON(ctx, http.MethodDelete, "/"+url.PathEscape(id), nil, nil)
+}
@@ -0,0 +1,71 @@
+package cocoonmail
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cocoonmail/cocoonmail-go/rest"
+)
+
+// ClientOption configures a Client built by NewSendClient
+type ClientOption func(*Client)
+
+// WithRetry retries sends that fail with a connection error or a 502, 503
+// or 504 response, making at most maxAttempts attempts in total. Before
+// retry n the client waits about baseDelay * 2^(n-1), with random jitter,
+// up to a minute, and gives up early when the context is done. Other responses, including
+// every 4xx other than 429, are never retried. A 429 is retried after the
+// delay in its Retry-After header, capped by WithMaxRetryAfter.
+func WithRetry(maxAttempts int, baseDelay time.Duration) ClientOption {
+	return func(cl *Client) {
+		cl.maxAttempts = maxAttempts
+		cl.baseDelay = baseDelay
+	}
+}
+
+// WithMaxRetryAfter caps how long a retry waits for the Retry-After of a
+// 429 response. The default is DefaultMaxRetryAfter.
+func WithMaxRetryAfter(d time.Duration) ClientOption {
+	return func(cl *Client) {
+		cl.maxRetryAfter = d
+	}
+}
+
+// WithHTTPClient sends through httpClient instead of DefaultClient, to set
+// timeouts, a proxy or a custom transport. Without it, or with a nil
+// httpClient, sends use DefaultClient with a DefaultTimeout timeout.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(cl *Client) {
+		if httpClient == nil {
+			cl.httpClient = nil
+			return
+		}
+		cl.httpClient = &rest.Client{HTTPClient: httpClient}
+	}
+}
+
+// WithTimeout bounds each send, retries included, to d. It composes with
+// the context given to SendWithContext: the earlier deadline applies. A
+// zero timeout means the client imposes none.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(cl *Client) {
+		cl.timeout = d
+	}
+}
+
+// WithAttachmentMIMEPolicy checks the inline attachments of every email
+// before it is sent by Send, SendWithContext, SendInRegion or SendBatch,
+// failing the send with an error naming the first disallowed attachment.
+// Entries are media types such as "application/pdf" or "image/*". A type in
+// deny is always rejected, even if it is in allow; a type in allow is
+// accepted; executables (see mail.DefaultBlockedContentTypes and
+// mail.DefaultBlockedExtensions) are rejected; and if allow is not empty,
+// every other type is rejected too. Executables are rejected even without
+// this option; list their type in allow to send them. Bodies sent with
+// SendBody are already marshaled and not checked.
+func WithAttachmentMIMEPolicy(allow, deny []string) ClientOption {
+	return func(cl *Client) {
+		cl.allowedTypes = append([]string(nil), allow...)
+		cl.deniedTypes = append([]string(nil), deny...)
+	}
+}
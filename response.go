@@ -0,0 +1,119 @@
+package cocoonmail
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cocoonmail/cocoonmail-go/rest"
+)
+
+// MailSendResponse holds the result of an accepted send.
+type MailSendResponse struct {
+	MessageID  string              // e.g. "5c1c4a3e-..."
+	StatusCode int                 // e.g. 202
+	Body       string              // raw response body
+	Headers    map[string][]string // response headers
+
+	// Rate limit state from the X-RateLimit-* headers; zero when absent
+	RateLimitLimit     int
+	RateLimitRemaining int
+	RateLimitReset     time.Time
+}
+
+// RateLimitError is returned when a send is still rate limited (HTTP 429)
+// after all retries. It unwraps to a *rest.RestError.
+type RateLimitError struct {
+	RetryAfter time.Duration // from the Retry-After header; zero if absent
+	Response   *rest.Response
+}
+
+// Error is the implementation of the error interface.
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited, retry after %v", e.RetryAfter)
+	}
+	return "rate limited"
+}
+
+// Unwrap returns the underlying *rest.RestError.
+func (e *RateLimitError) Unwrap() error {
+	return &rest.RestError{Response: e.Response}
+}
+
+// ErrInvalidResponse is wrapped by the error of a send whose 2xx response
+// has a body that is not valid JSON, e.g. because it was truncated. The API
+// accepted the email, so the MailSendResponse is returned too, without its
+// MessageID.
+var ErrInvalidResponse = errors.New("invalid response")
+
+// maxBodySnippet bounds how much of a response body an error quotes
+const maxBodySnippet = 64
+
+// parseSendResponse builds a MailSendResponse from a 2xx response.
+// A 429 is returned as a *RateLimitError, other status codes as a
+// *rest.RestError holding the response, and a non-empty body that is not
+// valid JSON as an error wrapping ErrInvalidResponse.
+func parseSendResponse(response *rest.Response) (*MailSendResponse, error) {
+	if response.StatusCode == http.StatusTooManyRequests {
+		retryAfter, _ := parseRetryAfter(response.Headers, time.Now())
+		return nil, &RateLimitError{RetryAfter: retryAfter, Response: response}
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, &rest.RestError{Response: response}
+	}
+
+	result := &MailSendResponse{
+		StatusCode: response.StatusCode,
+		Body:       response.Body,
+		Headers:    response.Headers,
+	}
+	header := http.Header(response.Headers)
+	result.RateLimitLimit, _ = strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	result.RateLimitRemaining, _ = strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		result.RateLimitReset = time.Unix(reset, 0)
+	}
+	var body struct {
+		MessageID string `json:"message_id"`
+	}
+	if strings.TrimSpace(response.Body) == "" {
+		return result, nil
+	}
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		snippet := response.Body
+		if len(snippet) > maxBodySnippet {
+			snippet = snippet[:maxBodySnippet] + "..."
+		}
+		return result, fmt.Errorf("%w: status %d, body %q: %v", ErrInvalidResponse, response.StatusCode, snippet, err)
+	}
+	result.MessageID = body.MessageID
+	return result, nil
+}
+
+// parseRetryAfter reads the Retry-After header, in either its delay-seconds
+// or HTTP-date form, as a delay from now. A date in the past is a zero delay.
+func parseRetryAfter(headers map[string][]string, now time.Time) (time.Duration, bool) {
+	value := strings.TrimSpace(http.Header(headers).Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := at.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
@@ -0,0 +1,257 @@
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redactedValue replaces sensitive header values and attachment data in recordings.
+const redactedValue = "[REDACTED]"
+
+// sensitiveHeaders are never written to a recording in clear text.
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// Recording is a single request/response exchange as written by a RequestRecorder.
+type Recording struct {
+	Time     time.Time         `json:"time"`
+	Request  RecordedRequest   `json:"request"`
+	Response *RecordedResponse `json:"response,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// RecordedRequest holds the redacted outgoing request.
+type RecordedRequest struct {
+	Method  string              `json:"method"`
+	URL     string              `json:"url"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    string              `json:"body,omitempty"`
+}
+
+// RecordedResponse holds the response received for a recorded request.
+type RecordedResponse struct {
+	StatusCode int                 `json:"status_code"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+	Body       string              `json:"body,omitempty"`
+}
+
+// RequestRecorder is an http.RoundTripper that writes every exchange to its writer
+// as one JSON line. Sensitive headers and the "data" field of attachments
+// are redacted before writing. Recording is best effort: a failed write
+// never changes the outcome of the request.
+// Use it as the Transport of the HTTPClient of a Client.
+type RequestRecorder struct {
+	Transport http.RoundTripper // defaults to http.DefaultTransport
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewRequestRecorder wraps transport and records every exchange to w.
+// A nil transport means http.DefaultTransport.
+func NewRequestRecorder(w io.Writer, transport http.RoundTripper) *RequestRecorder {
+	return &RequestRecorder{Transport: transport, w: w}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rr *RequestRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := rr.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close() // nolint
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	rec := Recording{
+		Time: time.Now().UTC(),
+		Request: RecordedRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: redactHeaders(req.Header),
+			Body:    string(redactBody(body)),
+		},
+	}
+
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		rec.Error = err.Error()
+		rr.write(rec) // nolint
+		return nil, err
+	}
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	res.Body.Close() // nolint
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(resBody))
+
+	rec.Response = &RecordedResponse{
+		StatusCode: res.StatusCode,
+		Headers:    redactHeaders(res.Header),
+		Body:       string(resBody),
+	}
+	rr.write(rec) // nolint: the request was sent, so report its response regardless
+	return res, nil
+}
+
+// WithRecorder returns a copy of c whose transport records every exchange to w.
+// A nil HTTPClient is treated as http.DefaultClient.
+func (c *Client) WithRecorder(w io.Writer) *Client {
+	base := c.HTTPClient
+	if base == nil {
+		base = http.DefaultClient
+	}
+	httpClient := *base
+	httpClient.Transport = NewRequestRecorder(w, base.Transport)
+	return &Client{HTTPClient: &httpClient}
+}
+
+func (rr *RequestRecorder) write(rec Recording) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	_, err = rr.w.Write(append(b, '\n'))
+	return err
+}
+
+// redactHeaders copies h, replacing the values of sensitive headers.
+func redactHeaders(h http.Header) map[string][]string {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(h))
+	for key, values := range h {
+		if sensitiveHeaders[http.CanonicalHeaderKey(key)] {
+			out[key] = []string{redactedValue}
+			continue
+		}
+		out[key] = append([]string(nil), values...)
+	}
+	return out
+}
+
+// redactBody blanks the "data" field of every entry in an "attachments"
+// array of a JSON body. Bodies that are not JSON objects are left as is.
+func redactBody(body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body
+	}
+	attachments, ok := payload["attachments"].([]interface{})
+	if !ok {
+		return body
+	}
+	for _, a := range attachments {
+		if att, ok := a.(map[string]interface{}); ok {
+			if _, present := att["data"]; present {
+				att["data"] = redactedValue
+			}
+		}
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return body
+	}
+	return b
+}
+
+// ErrReplayExhausted is returned by a Replayer once every recording has been served.
+var ErrReplayExhausted = errors.New("rest: no recorded responses left to replay")
+
+// Replayer is an http.RoundTripper that answers requests with previously
+// recorded responses, in the order they were recorded. It never touches the network.
+type Replayer struct {
+	mu         sync.Mutex
+	recordings []Recording
+}
+
+// ReplayFromFile loads a file written by a RequestRecorder and returns a Replayer serving it.
+func ReplayFromFile(path string) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint
+
+	var recordings []Recording
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var rec Recording
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return nil, err
+		}
+		recordings = append(recordings, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &Replayer{recordings: recordings}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rp *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	rp.mu.Lock()
+	if len(rp.recordings) == 0 {
+		rp.mu.Unlock()
+		return nil, ErrReplayExhausted
+	}
+	rec := rp.recordings[0]
+	rp.recordings = rp.recordings[1:]
+	rp.mu.Unlock()
+
+	if req.Body != nil {
+		req.Body.Close() // nolint
+	}
+	if rec.Response == nil {
+		return nil, errors.New(rec.Error)
+	}
+
+	header := make(http.Header, len(rec.Response.Headers))
+	for key, values := range rec.Response.Headers {
+		header[key] = append([]string(nil), values...)
+	}
+	return &http.Response{
+		Status:        http.StatusText(rec.Response.StatusCode),
+		StatusCode:    rec.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(rec.Response.Body)),
+		ContentLength: int64(len(rec.Response.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,133 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRequestRecorder(t *testing.T) {
+	t.Parallel()
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, "{\"message\": \"queued\"}")
+	}))
+	defer fakeServer.Close()
+
+	var recording bytes.Buffer
+	client := DefaultClient.WithRecorder(&recording)
+	request := Request{
+		Method:  Post,
+		BaseURL: fakeServer.URL + "/webhook/mail/send",
+		Headers: map[string]string{"Authorization": "Bearer API_KEY"},
+		Body:    []byte(`{"subject":"hi","attachments":[{"filename":"a.txt","data":"c2VjcmV0"}]}`),
+	}
+	response, err := client.Send(request)
+	if err != nil {
+		t.Fatalf("Send returned an error: %v", err)
+	}
+	if response.StatusCode != http.StatusAccepted || response.Body != "{\"message\": \"queued\"}" {
+		t.Errorf("Recorder altered the response: %+v", response)
+	}
+
+	line := recording.String()
+	if strings.Contains(line, "API_KEY") {
+		t.Error("Authorization header was not redacted")
+	}
+	if strings.Contains(line, "c2VjcmV0") {
+		t.Error("Attachment data was not redacted")
+	}
+
+	var rec Recording
+	if err := json.Unmarshal(recording.Bytes(), &rec); err != nil {
+		t.Fatalf("Recording is not a JSON line: %v", err)
+	}
+	if rec.Request.Method != "POST" || !strings.HasSuffix(rec.Request.URL, "/webhook/mail/send") {
+		t.Errorf("Unexpected recorded request: %+v", rec.Request)
+	}
+	if !strings.Contains(rec.Request.Body, `"subject":"hi"`) {
+		t.Error("Recorded body lost non-sensitive fields")
+	}
+	if rec.Response == nil || rec.Response.StatusCode != http.StatusAccepted {
+		t.Errorf("Unexpected recorded response: %+v", rec.Response)
+	}
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRequestRecorderWriteError(t *testing.T) {
+	t.Parallel()
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer fakeServer.Close()
+
+	client := (&Client{}).WithRecorder(failingWriter{})
+	response, err := client.Send(Request{Method: Post, BaseURL: fakeServer.URL})
+	if err != nil {
+		t.Fatalf("A failed recording should not fail the request: %v", err)
+	}
+	if response.StatusCode != http.StatusAccepted {
+		t.Errorf("Unexpected status code: %d", response.StatusCode)
+	}
+}
+
+func TestReplayFromFile(t *testing.T) {
+	t.Parallel()
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", r.URL.Path)
+		fmt.Fprint(w, r.URL.Path)
+	}))
+
+	var recording bytes.Buffer
+	client := DefaultClient.WithRecorder(&recording)
+	for _, path := range []string{"/first", "/second"} {
+		if _, err := client.Send(Request{Method: Get, BaseURL: fakeServer.URL + path}); err != nil {
+			t.Fatalf("Send returned an error: %v", err)
+		}
+	}
+	fakeServer.Close()
+
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	if err := ioutil.WriteFile(path, recording.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	replayer, err := ReplayFromFile(path)
+	if err != nil {
+		t.Fatalf("ReplayFromFile returned an error: %v", err)
+	}
+
+	replay := &Client{HTTPClient: &http.Client{Transport: replayer}}
+	for _, want := range []string{"/first", "/second"} {
+		response, err := replay.Send(Request{Method: Get, BaseURL: fakeServer.URL + want})
+		if err != nil {
+			t.Fatalf("Replay returned an error: %v", err)
+		}
+		if response.Body != want || response.Headers["X-Test"][0] != want {
+			t.Errorf("Replayed %q, expected %q", response.Body, want)
+		}
+	}
+	if _, err := replay.Send(Request{Method: Get, BaseURL: fakeServer.URL}); err == nil {
+		t.Error("Expected an error once the recording is exhausted")
+	}
+}
+
+func TestReplayFromMissingFile(t *testing.T) {
+	t.Parallel()
+	if _, err := ReplayFromFile(filepath.Join(os.TempDir(), "does-not-exist.jsonl")); err == nil {
+		t.Error("Expected an error for a missing recording")
+	}
+}
@@ -0,0 +1,46 @@
+package cocoonmail
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Suppression is an address that is not sent to, e.g. after a bounce or a
+// spam complaint
+type Suppression struct {
+	Email     string    `json:"email"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SuppressionsClient manages the suppression list. It shares the options,
+// headers and data residency handling of Client.
+type SuppressionsClient struct {
+	client *Client
+}
+
+// NewSuppressionsClient constructs a client for the suppressions API given an API key and options
+func NewSuppressionsClient(key string, opts ...ClientOption) *SuppressionsClient {
+	return &SuppressionsClient{client: newClient(key, "/webhook/suppressions", opts)}
+}
+
+// SetDataResidency sends the requests of c to the given region, as
+// SetDataResidency does for a request
+func (c *SuppressionsClient) SetDataResidency(region string) error {
+	return c.client.setDataResidency(region)
+}
+
+// GetSuppressions returns every suppressed address
+func (c *SuppressionsClient) GetSuppressions(ctx context.Context) ([]Suppression, error) {
+	var suppressions []Suppression
+	if err := c.client.doJSON(ctx, http.MethodGet, "", nil, &suppressions); err != nil {
+		return nil, err
+	}
+	return suppressions, nil
+}
+
+// AddSuppression stops sends to email, recording reason, such as "bounced" or "complaint"
+func (c *SuppressionsClient) AddSuppression(ctx context.Context, email, reason string) error {
+	return c.client.doJSON(ctx, http.MethodPost, "", map[string]string{"email": email, "reason": reason}, nil)
+}
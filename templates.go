@@ -0,0 +1,36 @@
+package cocoonmail
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/cocoonmail/cocoonmail-go/helpers/mail"
+)
+
+// TemplatesClient reads transactional templates. It shares the options,
+// headers and data residency handling of Client.
+type TemplatesClient struct {
+	client *Client
+}
+
+// NewTemplatesClient constructs a client for the templates API given an API key and options
+func NewTemplatesClient(key string, opts ...ClientOption) *TemplatesClient {
+	return &TemplatesClient{client: newClient(key, "/webhook/templates", opts)}
+}
+
+// SetDataResidency sends the requests of c to the given region, as
+// SetDataResidency does for a request
+func (c *TemplatesClient) SetDataResidency(region string) error {
+	return c.client.setDataResidency(region)
+}
+
+// GetTemplate returns the transactional template with the given ID, e.g. to
+// check a request with MailSendRequest.ValidateAgainstTemplate
+func (c *TemplatesClient) GetTemplate(ctx context.Context, id string) (*mail.Template, error) {
+	var template mail.Template
+	if err := c.client.doJSON(ctx, http.MethodGet, "/"+url.PathEscape(id), nil, &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// EventType is the kind of a webhook event
+type EventType string
+
+// Event types. EventUnknown stands for any type this package doesn't know.
+const (
+	EventDelivered   EventType = "delivered"
+	EventBounced     EventType = "bounced"
+	EventOpened      EventType = "opened"
+	EventClicked     EventType = "clicked"
+	EventSpamReport  EventType = "spam_report"
+	EventUnsubscribe EventType = "unsubscribe"
+	EventUnknown     EventType = "unknown"
+)
+
+// knownEventTypes are the types ParseEvent keeps as is
+var knownEventTypes = map[EventType]bool{
+	EventDelivered:   true,
+	EventBounced:     true,
+	EventOpened:      true,
+	EventClicked:     true,
+	EventSpamReport:  true,
+	EventUnsubscribe: true,
+}
+
+// Event is a webhook event posted by Cocoonmail
+type Event struct {
+	Type      EventType
+	MessageID string
+	Email     string
+	Timestamp time.Time
+	URL       string          // the clicked link, for EventClicked
+	Raw       json.RawMessage // the event as received, e.g. to handle EventUnknown
+}
+
+// ParseEvent decodes a webhook event body. Timestamps may be RFC3339
+// strings or Unix times in seconds. Types other than the Event* constants
+// become EventUnknown; the original is still in Raw.
+func ParseEvent(b []byte) (*Event, error) {
+	var body struct {
+		Type      EventType       `json:"type"`
+		MessageID string          `json:"message_id"`
+		Email     string          `json:"email"`
+		Timestamp json.RawMessage `json:"timestamp"`
+		URL       string          `json:"url"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		return nil, err
+	}
+	ts, err := parseTimestamp(body.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	event := &Event{
+		Type:      body.Type,
+		MessageID: body.MessageID,
+		Email:     body.Email,
+		Timestamp: ts,
+		URL:       body.URL,
+		Raw:       append(json.RawMessage(nil), b...),
+	}
+	if !knownEventTypes[event.Type] {
+		event.Type = EventUnknown
+	}
+	return event, nil
+}
+
+// parseTimestamp decodes an RFC3339 string or a number of Unix seconds.
+// A missing or null timestamp is the zero time.
+func parseTimestamp(raw json.RawMessage) (time.Time, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return time.Time{}, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("webhook: timestamp %q: %w", s, err)
+		}
+		return t, nil
+	}
+	seconds, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("webhook: timestamp %s: should be RFC3339 or Unix seconds", raw)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEvent(t *testing.T) {
+	for _, typ := range []EventType{EventDelivered, EventBounced, EventOpened, EventClicked, EventSpamReport, EventUnsubscribe} {
+		payload := fmt.Sprintf(`{"type":%q,"message_id":"msg-1","email":"jane@example.com","timestamp":"2030-01-02T15:04:05Z"}`, typ)
+		event, err := ParseEvent([]byte(payload))
+		if assert.Nil(t, err, string(typ)) {
+			assert.Equal(t, typ, event.Type)
+			assert.Equal(t, "msg-1", event.MessageID)
+			assert.Equal(t, "jane@example.com", event.Email)
+			assert.True(t, time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC).Equal(event.Timestamp))
+			assert.Equal(t, payload, string(event.Raw))
+		}
+	}
+}
+
+func TestParseEventClicked(t *testing.T) {
+	event, err := ParseEvent([]byte(`{"type":"clicked","message_id":"msg-1","timestamp":1893456000,"url":"https://example.com/offer"}`))
+	if assert.Nil(t, err) {
+		assert.Equal(t, EventClicked, event.Type)
+		assert.Equal(t, "https://example.com/offer", event.URL)
+		assert.Equal(t, time.Unix(1893456000, 0).UTC(), event.Timestamp)
+	}
+}
+
+func TestParseEventUnknownType(t *testing.T) {
+	event, err := ParseEvent([]byte(`{"type":"deferred","message_id":"msg-1"}`))
+	if assert.Nil(t, err) {
+		assert.Equal(t, EventUnknown, event.Type)
+		assert.Contains(t, string(event.Raw), `"deferred"`)
+	}
+}
+
+func TestParseEventMalformed(t *testing.T) {
+	for _, payload := range []string{`{"type":`, `{"type":"opened","timestamp":"yesterday"}`, `{"type":"opened","timestamp":true}`} {
+		_, err := ParseEvent([]byte(payload))
+		assert.NotNil(t, err, payload)
+	}
+}
@@ -0,0 +1,168 @@
+// Package webhook receives the event callbacks Cocoonmail posts to an
+// application, such as deliveries and bounces.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader holds the HMAC-SHA256 of the request body, keyed with the
+// webhook secret, in hex or base64
+const SignatureHeader = "X-Cocoonmail-Signature"
+
+// TimestampHeader holds the Unix time, in seconds, at which the event was
+// signed. When it is present the signature covers "<timestamp>.<body>".
+const TimestampHeader = "X-Cocoonmail-Timestamp"
+
+// DefaultTolerance is how old, or how far in the future, a signed timestamp
+// may be when VerifySignatureWithTimestamp is given no tolerance
+const DefaultTolerance = 5 * time.Minute
+
+// now returns the current time; tests replace it
+var now = time.Now
+
+// maxBodyBytes bounds the size of an event body read by Handler
+const maxBodyBytes = 1 << 20
+
+// HandlerOption configures a Handler
+type HandlerOption func(*handlerConfig)
+
+// handlerConfig holds the settings of a Handler
+type handlerConfig struct {
+	tolerance             time.Duration
+	allowMissingTimestamp bool
+}
+
+// WithTolerance sets how far a TimestampHeader may be from the current
+// time. A tolerance <= 0 means DefaultTolerance.
+func WithTolerance(tolerance time.Duration) HandlerOption {
+	return func(c *handlerConfig) {
+		c.tolerance = tolerance
+	}
+}
+
+// AllowMissingTimestamp accepts events without a TimestampHeader, checking
+// them with VerifySignature alone. Such events can be replayed, so only use
+// it while migrating senders that do not sign a timestamp yet.
+func AllowMissingTimestamp() HandlerOption {
+	return func(c *handlerConfig) {
+		c.allowMissingTimestamp = true
+	}
+}
+
+// Handler returns an http.Handler that checks the signature of each posted
+// event against secret, decodes it with ParseEvent and passes it to h.
+// Events are checked with VerifySignatureWithTimestamp and the
+// DefaultTolerance, or the one set with WithTolerance; events without a
+// TimestampHeader are rejected unless AllowMissingTimestamp is given.
+// Requests with a bad signature or timestamp or a malformed body get a 400
+// and h is not called; other methods than POST get a 405.
+func Handler(secret string, h func(Event), opts ...HandlerOption) http.Handler {
+	var config handlerConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			http.Error(w, "cannot read body", http.StatusBadRequest)
+			return
+		}
+		signature := r.Header.Get(SignatureHeader)
+		if ts := r.Header.Get(TimestampHeader); ts != "" {
+			err = VerifySignatureWithTimestamp(secret, payload, signature, ts, config.tolerance)
+		} else if config.allowMissingTimestamp {
+			err = VerifySignature(secret, payload, signature)
+		} else {
+			err = ErrMissingTimestamp
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		event, err := ParseEvent(payload)
+		if err != nil {
+			http.Error(w, "malformed event", http.StatusBadRequest)
+			return
+		}
+		h(*event)
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// Errors returned by VerifySignature and VerifySignatureWithTimestamp, and
+// reported by Handler
+var (
+	ErrMalformedSignature = errors.New("webhook: malformed signature")
+	ErrSignatureMismatch  = errors.New("webhook: signature mismatch")
+	ErrMissingTimestamp   = errors.New("webhook: missing timestamp")
+	ErrMalformedTimestamp = errors.New("webhook: malformed timestamp")
+	ErrStaleTimestamp     = errors.New("webhook: timestamp outside the tolerance")
+)
+
+// VerifySignature checks that signatureHeader is the HMAC-SHA256 of payload
+// keyed with secret, encoded as hex or standard base64. The comparison runs
+// in constant time.
+func VerifySignature(secret string, payload []byte, signatureHeader string) error {
+	got, err := decodeSignature(strings.TrimSpace(signatureHeader))
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload) // nolint
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+// VerifySignatureWithTimestamp checks that sig is the HMAC-SHA256 of
+// "<ts>.<payload>" keyed with secret, as VerifySignature does, and that ts,
+// a Unix time in seconds, is within tolerance of the current time in either
+// direction, so captured events cannot be replayed later. A tolerance <= 0
+// means DefaultTolerance.
+func VerifySignatureWithTimestamp(secret string, payload []byte, sig, ts string, tolerance time.Duration) error {
+	ts = strings.TrimSpace(ts)
+	seconds, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrMalformedTimestamp
+	}
+	signed := make([]byte, 0, len(ts)+1+len(payload))
+	signed = append(append(append(signed, ts...), '.'), payload...)
+	if err := VerifySignature(secret, signed, sig); err != nil {
+		return err
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	age := now().Sub(time.Unix(seconds, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrStaleTimestamp
+	}
+	return nil
+}
+
+// decodeSignature decodes a SHA-256 sized signature in hex or base64
+func decodeSignature(signature string) ([]byte, error) {
+	if b, err := hex.DecodeString(signature); err == nil && len(b) == sha256.Size {
+		return b, nil
+	}
+	if b, err := base64.StdEncoding.DecodeString(signature); err == nil && len(b) == sha256.Size {
+		return b, nil
+	}
+	return nil, ErrMalformedSignature
+}
@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "whsec_test"
+
+// sign returns the hex HMAC-SHA256 of payload keyed with testSecret
+func sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(payload)) // nolint
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// post sends payload to a Handler with the given timestamp and signature
+// headers; an empty ts sends no timestamp
+func post(h http.Handler, ts, payload, signature string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/cocoonmail", strings.NewReader(payload))
+	req.Header.Set(SignatureHeader, signature)
+	if ts != "" {
+		req.Header.Set(TimestampHeader, ts)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	var events []Event
+	h := Handler(testSecret, func(e Event) { events = append(events, e) })
+
+	payload := `{"type":"delivered","message_id":"msg-1","email":"jane@example.com"}`
+	ts := unixNow()
+	rec := post(h, ts, payload, signAt(ts, payload))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, EventDelivered, events[0].Type)
+		assert.Equal(t, "msg-1", events[0].MessageID)
+		assert.Equal(t, "jane@example.com", events[0].Email)
+	}
+}
+
+func TestHandlerBadSignature(t *testing.T) {
+	called := false
+	h := Handler(testSecret, func(Event) { called = true })
+	payload := `{"type":"delivered","message_id":"msg-1"}`
+	ts := unixNow()
+
+	for _, signature := range []string{"", "not-hex", signAt(ts, payload+" "), signAt(ts, payload)[2:], sign(payload)} {
+		rec := post(h, ts, payload, signature)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "signature %q", signature)
+	}
+	assert.False(t, called, "the callback should not run for a bad signature")
+}
+
+func TestHandlerMalformedBody(t *testing.T) {
+	called := false
+	h := Handler(testSecret, func(Event) { called = true })
+	ts := unixNow()
+	rec := post(h, ts, `{"type":`, signAt(ts, `{"type":`))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.False(t, called)
+}
+
+func TestHandlerMethod(t *testing.T) {
+	h := Handler(testSecret, func(Event) {})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhooks/cocoonmail", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"type":"opened","message_id":"msg-1"}`)
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write(payload) // nolint
+	sum := mac.Sum(nil)
+
+	assert.Nil(t, VerifySignature(testSecret, payload, hex.EncodeToString(sum)))
+	assert.Nil(t, VerifySignature(testSecret, payload, strings.ToUpper(hex.EncodeToString(sum))))
+	assert.Nil(t, VerifySignature(testSecret, payload, base64.StdEncoding.EncodeToString(sum)))
+
+	tampered := []byte(`{"type":"opened","message_id":"msg-2"}`)
+	assert.Equal(t, ErrSignatureMismatch, VerifySignature(testSecret, tampered, hex.EncodeToString(sum)))
+	assert.Equal(t, ErrSignatureMismatch, VerifySignature("other-secret", payload, hex.EncodeToString(sum)))
+
+	for _, header := range []string{"", "zz", hex.EncodeToString(sum[:16]), "sha256=" + hex.EncodeToString(sum)} {
+		assert.Equal(t, ErrMalformedSignature, VerifySignature(testSecret, payload, header), "header %q", header)
+	}
+}
+
+// signAt returns the hex signature of payload signed at ts
+func signAt(ts, payload string) string {
+	return sign(ts + "." + payload)
+}
+
+// unixNow returns the current Unix time as a TimestampHeader value
+func unixNow() string {
+	return strconv.FormatInt(time.Now().Unix(), 10)
+}
+
+func TestVerifySignatureWithTimestamp(t *testing.T) {
+	fixed := time.Unix(1893456000, 0)
+	now = func() time.Time { return fixed }
+	t.Cleanup(func() { now = time.Now })
+
+	payload := `{"type":"clicked","message_id":"msg-1"}`
+	fresh := strconv.FormatInt(fixed.Add(-time.Minute).Unix(), 10)
+	assert.Nil(t, VerifySignatureWithTimestamp(testSecret, []byte(payload), signAt(fresh, payload), fresh, 0))
+
+	stale := strconv.FormatInt(fixed.Add(-6*time.Minute).Unix(), 10)
+	assert.Equal(t, ErrStaleTimestamp, VerifySignatureWithTimestamp(testSecret, []byte(payload), signAt(stale, payload), stale, 0))
+	assert.Nil(t, VerifySignatureWithTimestamp(testSecret, []byte(payload), signAt(stale, payload), stale, 10*time.Minute), "a larger tolerance should accept it")
+
+	future := strconv.FormatInt(fixed.Add(6*time.Minute).Unix(), 10)
+	assert.Equal(t, ErrStaleTimestamp, VerifySignatureWithTimestamp(testSecret, []byte(payload), signAt(future, payload), future, 0))
+
+	// the timestamp is signed, so it cannot be refreshed by an attacker
+	assert.Equal(t, ErrSignatureMismatch, VerifySignatureWithTimestamp(testSecret, []byte(payload), signAt(stale, payload), fresh, 0))
+	assert.Equal(t, ErrMalformedTimestamp, VerifySignatureWithTimestamp(testSecret, []byte(payload), signAt(fresh, payload), "yesterday", 0))
+}
+
+func TestHandlerTimestamp(t *testing.T) {
+	called := 0
+	h := Handler(testSecret, func(Event) { called++ })
+	payload := `{"type":"delivered","message_id":"msg-1"}`
+
+	for ts, want := range map[string]int{
+		strconv.FormatInt(time.Now().Unix(), 10):                 http.StatusOK,
+		strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10): http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/cocoonmail", strings.NewReader(payload))
+		req.Header.Set(SignatureHeader, signAt(ts, payload))
+		req.Header.Set(TimestampHeader, ts)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, want, rec.Code, "timestamp %s", ts)
+	}
+	assert.Equal(t, 1, called)
+}
+
+func TestHandlerMissingTimestamp(t *testing.T) {
+	called := 0
+	payload := `{"type":"delivered","message_id":"msg-1"}`
+
+	rec := post(Handler(testSecret, func(Event) { called++ }), "", payload, sign(payload))
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "a missing timestamp should be rejected by default")
+	assert.Contains(t, rec.Body.String(), ErrMissingTimestamp.Error())
+	assert.Equal(t, 0, called)
+
+	rec = post(Handler(testSecret, func(Event) { called++ }, AllowMissingTimestamp()), "", payload, sign(payload))
+	assert.Equal(t, http.StatusOK, rec.Code, "AllowMissingTimestamp should accept events without a timestamp")
+	assert.Equal(t, 1, called)
+}
+
+func TestHandlerWithTolerance(t *testing.T) {
+	payload := `{"type":"delivered","message_id":"msg-1"}`
+	ts := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+
+	rec := post(Handler(testSecret, func(Event) {}), ts, payload, signAt(ts, payload))
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "the default tolerance should reject it")
+	rec = post(Handler(testSecret, func(Event) {}, WithTolerance(time.Hour)), ts, payload, signAt(ts, payload))
+	assert.Equal(t, http.StatusOK, rec.Code, "a larger tolerance should accept it")
+}